@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// archiveExts are the archive extensions recognized on inputs, longest first.
+var archiveExts = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// archiveExt returns the archive extension of name, or "" if it isn't an
+// archive.
+func archiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(lower, ext) {
+			return name[len(name)-len(ext):]
+		}
+	}
+	return ""
+}
+
+// runArchive writes a copy of a zip or tar archive with every image padded.
+// Images keep their name and format, other entries are copied unchanged. It
+// returns the hex SHA-256 digest of the written archive.
+func runArchive(j job) (string, error) {
+	if j.output == "-" {
+		return "", fmt.Errorf("archives can't be written to stdout")
+	}
+
+	hash := sha256.New()
+	write := func(w io.Writer) error {
+		w = io.MultiWriter(w, hash)
+		switch strings.ToLower(archiveExt(j.input)) {
+		case ".zip":
+			return padZip(j, w)
+		case ".tar":
+			return padTar(j, w, false)
+		default:
+			return padTar(j, w, true)
+		}
+	}
+
+	if j.backup {
+		if err := backup(j.input); err != nil {
+			return "", withExitCode(exitWrite, err)
+		}
+	}
+	if err := writeAtomic(j.output, write); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func padZip(j job, w io.Writer) error {
+	zr, err := zip.OpenReader(j.input)
+	if err != nil {
+		return withExitCode(exitRead, fmt.Errorf("failed to open input archive: %w", err))
+	}
+	defer zr.Close()
+
+	zw := zip.NewWriter(w)
+	zw.SetComment(zr.Comment)
+	for _, f := range zr.File {
+		data, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+
+		padded, ok, err := padEntry(j, f.Name, data)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return withExitCode(exitWrite, fmt.Errorf("failed to write output archive: %w", err))
+			}
+			continue
+		}
+
+		// The checksum and sizes are recomputed for the new content.
+		header := f.FileHeader
+		header.CRC32, header.CompressedSize64, header.UncompressedSize64 = 0, 0, 0
+		entry, err := zw.CreateHeader(&header)
+		if err == nil {
+			_, err = entry.Write(padded)
+		}
+		if err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to write output archive: %w", err))
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("failed to write output archive: %w", err))
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	if f.FileInfo().IsDir() {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to read %s from input archive: %w", f.Name, err))
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to read %s from input archive: %w", f.Name, err))
+	}
+	return data, nil
+}
+
+func padTar(j job, w io.Writer, compressed bool) error {
+	f, err := os.Open(j.input)
+	if err != nil {
+		return withExitCode(exitRead, fmt.Errorf("failed to open input archive: %w", err))
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	var gw *gzip.Writer
+	if compressed {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to open input archive: %w", err))
+		}
+		defer gr.Close()
+		r = gr
+
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to read input archive: %w", err))
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to read %s from input archive: %w", header.Name, err))
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			padded, ok, err := padEntry(j, header.Name, data)
+			if err != nil {
+				return err
+			}
+			if ok {
+				data = padded
+				header.Size = int64(len(data))
+			}
+		}
+
+		err = tw.WriteHeader(header)
+		if err == nil {
+			_, err = tw.Write(data)
+		}
+		if err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to write output archive: %w", err))
+		}
+	}
+
+	err = tw.Close()
+	if err == nil && gw != nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("failed to write output archive: %w", err))
+	}
+	return nil
+}
+
+// padEntry pads an archive entry and returns the encoded result, or false if
+// the entry isn't an image. The format follows the entry's extension, or the
+// format it was decoded from.
+func padEntry(j job, name string, data []byte) ([]byte, bool, error) {
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+	if _, _, err := uvpad.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return nil, false, nil
+	}
+
+	var padded bytes.Buffer
+	err := pad(j, j.input+"/"+name, bytes.NewReader(data), func(img image.Image, format string) error {
+		if f := uvpad.FormatForPath(name); f != "" {
+			format = f
+		}
+		if err := uvpad.Encode(&padded, img, format); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to encode %s: %w", name, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return padded.Bytes(), true, nil
+}
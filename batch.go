@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// runBatch processes the jobs with the given number of concurrent workers and
+// returns their results in job order.
+func runBatch(jobs []job, workers int) []result {
+	results := make([]result, len(jobs))
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = process(jobs[i], len(jobs) > 1)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return results
+}
+
+func process(j job, batch bool) result {
+	defer finishProgress(j.input)
+
+	if j.checkpoint != nil {
+		if digest, ok := j.checkpoint.completed(j); ok {
+			logEvent(levelNormal, "skipped", j.input, fields{"output": j.output}, "Skipping %s: completed by an earlier run\n", j.input)
+			return result{job: j, digest: digest, skipped: true}
+		}
+	}
+
+	var inputStamp fileStamp
+	var inputDigest string
+	if j.state != nil {
+		if digest, ok := j.state.current(j); ok {
+			logEvent(levelNormal, "skipped", j.input, fields{"output": j.output}, "Skipping %s: %s is up to date\n", j.input, j.output)
+			return result{job: j, digest: digest, skipped: true}
+		}
+		inputStamp, _ = stat(j.input)
+		inputDigest, _ = fileDigest(j.input)
+	}
+
+	logEvent(levelNormal, "started", j.input, fields{"output": j.output}, "")
+	start := time.Now()
+	digest, err := run(j)
+	r := result{job: j, digest: digest, duration: time.Since(start), err: err}
+
+	if err != nil {
+		if batch {
+			logError(j.input, err)
+		}
+		return r
+	}
+
+	if j.state != nil {
+		j.state.record(j, inputStamp, inputDigest, digest)
+	}
+	if j.checkpoint != nil {
+		if err := j.checkpoint.add(j, digest); err != nil {
+			logError(j.input, err)
+		}
+	}
+
+	logEvent(levelNormal, "completed", j.input, fields{
+		"output":      j.output,
+		"duration_ms": r.duration.Milliseconds(),
+		"sha256":      digest,
+	}, "Execution time: %v\nSaved padded image to %s\n", r.duration, j.output)
+	return r
+}
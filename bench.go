@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/urfave/cli/v3"
+)
+
+// benchCommand times every algorithm on synthetic textures, so that
+// throughput can be compared between machines and releases.
+var benchCommand = &cli.Command{
+	Name:  "bench",
+	Usage: "Report the throughput and memory of the algorithms on synthetic textures, with the dilation settings of the other flags",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "sizes",
+			Value: "512,1024,2048,4096",
+			Usage: "Comma separated sizes of the square textures, such as 512,1024, or WxH",
+			Validator: func(s string) error {
+				_, err := parseSizes(s)
+				return err
+			},
+		},
+		&cli.StringFlag{
+			Name:  "algorithms",
+			Usage: "Comma separated algorithms to time, by default all of them",
+			Validator: func(s string) error {
+				for _, name := range strings.Split(s, ",") {
+					if _, err := uvpad.Lookup(strings.TrimSpace(name)); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		&cli.IntFlag{
+			Name:  "runs",
+			Value: 3,
+			Usage: "Times every algorithm is run on every size, of which the fastest is reported",
+			Validator: func(runs int64) error {
+				if runs < 1 {
+					return fmt.Errorf("--runs must be at least 1, got %d", runs)
+				}
+				return nil
+			},
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.NArg() != 0 {
+			return fmt.Errorf("bench takes no arguments, usage: uvpad bench [--sizes 512,1024]")
+		}
+		limitThreads(cmd)
+		jsonLog = cmd.String("log-format") == "json"
+
+		sizes, _ := parseSizes(cmd.String("sizes"))
+		algorithms := uvpad.Algorithms()
+		if names := cmd.String("algorithms"); names != "" {
+			algorithms = strings.Split(names, ",")
+			for i := range algorithms {
+				algorithms[i] = strings.TrimSpace(algorithms[i])
+			}
+		}
+		opts := newSettings(cmd).opts
+		runs := int(cmd.Int("runs"))
+
+		prof, err := startProfiles(cmd)
+		if err != nil {
+			return withExitCode(exitWrite, err)
+		}
+		if !jsonLog {
+			printf("%-10s %-11s %10s %10s %12s %12s\n", "algorithm", "size", "time", "Mpixels/s", "allocated", "estimated")
+		}
+		for _, size := range sizes {
+			src := benchTexture(size[0], size[1])
+			for _, name := range algorithms {
+				alg, _ := uvpad.Lookup(name)
+				elapsed, allocated := benchRun(alg, src, opts, runs)
+
+				pixels := float64(size[0]) * float64(size[1])
+				throughput := pixels / 1e6 / elapsed.Seconds()
+				estimated := 2*int64(pixels)*4 + uvpad.EstimateMemory(alg, size[0], size[1], 1, opts)
+				logEvent(levelNormal, "bench", "", fields{
+					"algorithm": name,
+					"width":     size[0],
+					"height":    size[1],
+					"seconds":   elapsed.Seconds(),
+					"mpixels":   throughput,
+					"allocated": allocated,
+					"estimated": estimated,
+					"threads":   runtime.GOMAXPROCS(0),
+				}, "%-10s %-11s %10v %10.1f %12s %12s\n", name, fmt.Sprintf("%dx%d", size[0], size[1]), elapsed.Round(time.Microsecond), throughput, formatSize(allocated), formatSize(estimated))
+			}
+			uvpad.Release(src)
+		}
+		if err := prof.stop(); err != nil {
+			return withExitCode(exitWrite, err)
+		}
+		return nil
+	},
+}
+
+// benchRun processes src with alg runs times and returns the fastest time
+// and the bytes allocated by that run.
+func benchRun(alg uvpad.Algorithm, src uvpad.Image, opts uvpad.Options, runs int) (time.Duration, int64) {
+	var best time.Duration
+	var allocated int64
+	for i := 0; i < runs; i++ {
+		// Collect the garbage of the previous run so it isn't timed.
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		dst := alg.Process(src, opts)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		uvpad.Release(dst)
+
+		if i == 0 || elapsed < best {
+			best, allocated = elapsed, int64(after.TotalAlloc-before.TotalAlloc)
+		}
+	}
+	return best, allocated
+}
+
+// benchTexture returns a width×height texture of opaque disks and
+// rectangles of random colors on a transparent background, about half of it
+// covered. The same size always gives the same texture.
+func benchTexture(width, height int) *uvpad.Buffer[uint8] {
+	r := rand.New(rand.NewPCG(uint64(width), uint64(height)))
+	b := uvpad.NewBuffer[uint8](width, height)
+
+	// Islands of about 1/16 of the smaller side, the density of a
+	// typical UV layout.
+	scale := max(min(width, height)/16, 2)
+	for range width * height / (scale * scale) {
+		cx, cy := r.IntN(width), r.IntN(height)
+		w, h := 1+r.IntN(scale), 1+r.IntN(scale)
+		disk := r.IntN(2) == 0
+		color := [4]uint8{uint8(r.UintN(256)), uint8(r.UintN(256)), uint8(r.UintN(256)), 255}
+		for y := max(cy-h, 0); y < min(cy+h, height); y++ {
+			for x := max(cx-w, 0); x < min(cx+w, width); x++ {
+				if dx, dy := float64(x-cx)/float64(w), float64(y-cy)/float64(h); disk && dx*dx+dy*dy > 1 {
+					continue
+				}
+				copy(b.Pix[(y*width+x)*4:], color[:])
+			}
+		}
+	}
+	return b
+}
+
+// parseSizes parses a comma separated list of image sizes.
+func parseSizes(s string) ([][2]int, error) {
+	var sizes [][2]int
+	for _, field := range strings.Split(s, ",") {
+		width, height, err := parseDimensions(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, [2]int{width, height})
+	}
+	return sizes, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// checkpointEntry is one line of a checkpoint file: a job completed by an
+// earlier, interrupted run.
+type checkpointEntry struct {
+	Input        string `json:"input"`
+	Output       string `json:"output"`
+	Settings     string `json:"settings"`
+	OutputDigest string `json:"output_sha256"`
+}
+
+// checkpoint records completed jobs while a batch runs, one JSON line per job
+// synced to disk, so that rerunning an interrupted batch skips them. The file
+// is removed once a run completes without failures.
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	done map[checkpointKey]string
+}
+
+// checkpointKey identifies a job by its files and settings.
+type checkpointKey struct {
+	input, output, settings string
+}
+
+func openCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{path: path, done: map[checkpointKey]string{}}
+
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry checkpointEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+				// A line cut short by a crash.
+				continue
+			}
+			c.done[checkpointKey{entry.Input, entry.Output, entry.Settings}] = entry.OutputDigest
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+		}
+	}
+
+	c.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint file: %w", err)
+	}
+	return c, nil
+}
+
+// completed returns the digest of the job's output if an earlier run
+// completed the job with the same settings and the output is still the file
+// it wrote.
+func (c *checkpoint) completed(j job) (string, bool) {
+	if j.output == "-" {
+		return "", false
+	}
+
+	c.mu.Lock()
+	recorded, ok := c.done[checkpointKey{j.input, j.output, j.fingerprint}]
+	c.mu.Unlock()
+	if !ok {
+		return "", false
+	}
+
+	digest, err := fileDigest(j.output)
+	if err != nil || digest != recorded {
+		return "", false
+	}
+	return digest, true
+}
+
+// add records a completed job.
+func (c *checkpoint) add(j job, digest string) error {
+	line, err := json.Marshal(checkpointEntry{j.input, j.output, j.fingerprint, digest})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to update checkpoint file: %w", err)
+	}
+	if err := c.file.Sync(); err != nil {
+		return fmt.Errorf("failed to update checkpoint file: %w", err)
+	}
+	return nil
+}
+
+// close closes the checkpoint file, removing it if the run is finished.
+func (c *checkpoint) close(finished bool) error {
+	if err := c.file.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if finished {
+		return os.Remove(c.path)
+	}
+	return nil
+}
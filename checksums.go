@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksum is the SHA-256 digest of a produced file.
+type checksum struct {
+	path   string
+	digest string
+}
+
+// writeChecksums writes a manifest in the format of sha256sum, so that it can
+// be verified with `sha256sum -c` from the manifest's directory.
+func writeChecksums(manifest string, checksums []checksum) error {
+	dir, err := filepath.Abs(filepath.Dir(manifest))
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, c := range checksums {
+		name := c.path
+		if abs, err := filepath.Abs(c.path); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				name = rel
+			}
+		}
+		fmt.Fprintf(&sb, "%s  %s\n", c.digest, filepath.ToSlash(name))
+	}
+
+	if err := os.WriteFile(manifest, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+	return nil
+}
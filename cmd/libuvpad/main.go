@@ -0,0 +1,282 @@
+// Command libuvpad exposes the uvpad dilation to C and everything that can
+// call C (C++, C#, Unreal and Unity plugins). Build it with:
+//
+//	go build -buildmode=c-shared -o libuvpad.so ./cmd/libuvpad
+//
+// which also writes libuvpad.h. Buffers are tightly packed, non-premultiplied
+// RGBA and are dilated in place.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+typedef struct {
+	// Name of the dilation algorithm, NULL for the default ("jfa").
+	const char* algorithm;
+	// Bias of signed data stored in the color channels, 0 to disable.
+	double bias;
+	// Space colors are blended in: 0 sRGB values, 1 linear light.
+	int color_space;
+	// Non-zero to treat the image as a normal map and renormalize dilated
+	// vectors. normal_z is 0 keep, 1 positive, 2 reconstruct.
+	int normal_map;
+	int normal_z;
+	// Pixels the coverage is opened and closed by before dilating, and the
+	// opaque areas are shrunk by, 0 to disable.
+	double open;
+	double close;
+	double erode;
+	// Maximum dilation distance in pixels, 0 for unlimited.
+	double radius;
+	// Dilate images larger than this many pixels on a side in tiles with a
+	// margin of the radius, 0 for the whole image.
+	int tile;
+	// Minimum alpha (0 to 1) of the pixels that are dilated, 0 for opaque only.
+	double alpha_threshold;
+	// Non-zero to keep the alpha channel of the input and only dilate color.
+	int keep_alpha;
+	// Non-zero to blend partially transparent pixels over the dilated color.
+	int composite;
+	// Bits of the dilated channels (1 R, 2 G, 4 B, 8 A), the others are kept,
+	// 0 for all.
+	int channels;
+	// Non-zero if the colors are premultiplied by alpha.
+	int premultiplied;
+	// Neighbours beyond the border: 0 skip, 1 wrap (tiling), 2 mirror.
+	int edge;
+	// Jump flood variant such as "1+jfa+2", NULL for the plain jump flood.
+	const char* jfa_variant;
+	// Pipeline of operations such as "erode:1,dilate:16,blur:2", NULL for a
+	// single dilation.
+	const char* ops;
+	// Nearest pixel distance: 0 euclidean, 1 manhattan, 2 chebyshev.
+	int metric;
+	// Number of nearest opaque pixels whose colors are blended, 0 or 1 for
+	// the nearest only.
+	int blend;
+	// Fade the dilated colors toward fade_color (RGBA, 0 to 1) over this
+	// many pixels, 0 to disable. fade_falloff is 0 gaussian, 1 linear.
+	double fade_distance;
+	int fade_falloff;
+	double fade_color[4];
+	// Pixels over which the alpha ramps down before the radius, 0 for a hard
+	// edge.
+	double feather;
+	// Non-zero to output the signed distance field of the coverage instead
+	// of dilating, spread pixels from the edge to 0 and 1 (0 for the default).
+	int sdf;
+	double spread;
+	// Non-zero for a multi-channel signed distance field, implies sdf.
+	int msdf;
+	// Side of the edge above 0.5 in the field: 0 inside, 1 outside.
+	int sdf_sign;
+	// Bits of the channels holding the field like channels (0 for RGB), the
+	// other color channels are kept and alpha is otherwise opaque.
+	int sdf_channels;
+	// Fill of unreached pixels: 0 transparent, 1 average of the opaque
+	// pixels, 2 background_color (RGBA, 0 to 1).
+	int background;
+	double background_color[4];
+	// Neighbourhood of the gimp algorithm: 4 or 8 connectivity (0 for 4) and
+	// kernel radius (0 for 1).
+	int connectivity;
+	int kernel;
+	// Relaxation iterations per level of the diffusion algorithm, 0 for the
+	// default.
+	int iterations;
+	// Coverage deciding which pixels are seeds: 0 alpha, 1 luminance above
+	// threshold (0 to 1).
+	int seed;
+	double threshold;
+	// Non-zero to seed by key_color (RGB, 0 to 1): pixels within
+	// key_tolerance of it on every channel are empty, the others opaque.
+	int key;
+	double key_color[3];
+	double key_tolerance;
+	// Coverage of width*height bytes deciding which pixels are dilated
+	// instead of the alpha channel, NULL to use the alpha channel.
+	const uint8_t* mask;
+	// Non-zero to dilate the color channels as independent data, each with a
+	// coverage in channel_masks (red, green, blue; NULL for the shared one)
+	// separately.
+	int packed;
+	const uint8_t* channel_masks[3];
+	// Non-zero to invert the coverage of the alpha channel or mask.
+	int invert;
+} uvpad_options;
+*/
+import "C"
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"unsafe"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+func main() {}
+
+var (
+	lastErrorMu sync.Mutex
+	lastError   *C.char
+)
+
+func setLastError(err error) C.int {
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+
+	if lastError != nil {
+		C.free(unsafe.Pointer(lastError))
+		lastError = nil
+	}
+	if err == nil {
+		return 0
+	}
+	lastError = C.CString(err.Error())
+	return -1
+}
+
+// uvpad_last_error returns the message of the last failed call, or NULL. The
+// string is owned by the library and valid until the next call.
+//
+//export uvpad_last_error
+func uvpad_last_error() *C.char {
+	lastErrorMu.Lock()
+	defer lastErrorMu.Unlock()
+
+	return lastError
+}
+
+// uvpad_dilate dilates an 8-bit RGBA buffer of width*height*4 bytes in place.
+// It returns 0 on success and -1 on failure, see uvpad_last_error.
+//
+//export uvpad_dilate
+func uvpad_dilate(buffer *C.uint8_t, width, height C.int, opts *C.uvpad_options) C.int {
+	return setLastError(dilate((*uint8)(unsafe.Pointer(buffer)), int(width), int(height), opts))
+}
+
+// uvpad_dilate16 dilates a 16-bit RGBA buffer of width*height*4 samples in
+// place.
+//
+//export uvpad_dilate16
+func uvpad_dilate16(buffer *C.uint16_t, width, height C.int, opts *C.uvpad_options) C.int {
+	return setLastError(dilate((*uint16)(unsafe.Pointer(buffer)), int(width), int(height), opts))
+}
+
+// uvpad_dilatef dilates a float RGBA buffer of width*height*4 samples in
+// place. Alpha is opaque at 1.0, color channels may exceed 1.0.
+//
+//export uvpad_dilatef
+func uvpad_dilatef(buffer *C.float, width, height C.int, opts *C.uvpad_options) C.int {
+	return setLastError(dilate((*float32)(unsafe.Pointer(buffer)), int(width), int(height), opts))
+}
+
+func dilate[T uvpad.Sample](buffer *T, width, height int, copts *C.uvpad_options) error {
+	if buffer == nil || width <= 0 || height <= 0 {
+		return errors.New("invalid buffer")
+	}
+
+	algorithm := "jfa"
+	var opts uvpad.Options
+	if copts != nil {
+		if copts.algorithm != nil {
+			algorithm = C.GoString(copts.algorithm)
+		}
+		opts.Bias = float64(copts.bias)
+		opts.ColorSpace = uvpad.ColorSpace(copts.color_space)
+		opts.NormalMap = copts.normal_map != 0
+		opts.NormalZ = uvpad.NormalZ(copts.normal_z)
+		opts.Open = float64(copts.open)
+		opts.Close = float64(copts.close)
+		opts.Erode = float64(copts.erode)
+		opts.Radius = float64(copts.radius)
+		opts.Tile = int(copts.tile)
+		opts.AlphaThreshold = float64(copts.alpha_threshold)
+		opts.KeepAlpha = copts.keep_alpha != 0
+		opts.Composite = copts.composite != 0
+		opts.Channels = uvpad.Channels(copts.channels)
+		opts.Premultiplied = copts.premultiplied != 0
+		opts.Edge = uvpad.Edge(copts.edge)
+		opts.Metric = uvpad.Metric(copts.metric)
+		opts.Blend = int(copts.blend)
+		opts.FadeDistance = float64(copts.fade_distance)
+		opts.Fade = uvpad.Falloff(copts.fade_falloff)
+		for i, v := range copts.fade_color {
+			opts.FadeColor[i] = float64(v)
+		}
+		opts.Connectivity = int(copts.connectivity)
+		opts.Kernel = int(copts.kernel)
+		opts.Iterations = int(copts.iterations)
+		opts.Seed = uvpad.Seed(copts.seed)
+		opts.Threshold = float64(copts.threshold)
+		if copts.key != 0 {
+			var key uvpad.Color
+			for i, v := range copts.key_color {
+				key[i] = float64(v)
+			}
+			opts.KeyColor = &key
+			opts.KeyTolerance = float64(copts.key_tolerance)
+		}
+		opts.Invert = copts.invert != 0
+		if copts.mask != nil {
+			opts.Mask = grayMask(copts.mask, width, height)
+		}
+		opts.Packed = copts.packed != 0
+		for c, mask := range copts.channel_masks {
+			if mask != nil {
+				opts.ChannelMasks[c] = grayMask(mask, width, height)
+			}
+		}
+		opts.Feather = float64(copts.feather)
+		opts.Spread = float64(copts.spread)
+		opts.MSDF = copts.msdf != 0
+		opts.SDFSign = uvpad.Sign(copts.sdf_sign)
+		opts.SDFChannels = uvpad.Channels(copts.sdf_channels)
+		opts.Background.Mode = uvpad.BackgroundMode(copts.background)
+		for i, v := range copts.background_color {
+			opts.Background.Color[i] = float64(v)
+		}
+		if copts.jfa_variant != nil {
+			variant, err := uvpad.ParseJFAVariant(C.GoString(copts.jfa_variant))
+			if err != nil {
+				return err
+			}
+			opts.JFA = variant
+		}
+	}
+
+	alg, err := uvpad.Lookup(algorithm)
+	if err != nil {
+		return err
+	}
+	if copts != nil && copts.ops != nil {
+		ops, err := uvpad.ParseOps(C.GoString(copts.ops))
+		if err != nil {
+			return err
+		}
+		alg = uvpad.Pipeline(alg, ops)
+	}
+
+	pix := unsafe.Slice(buffer, width*height*4)
+	src := &uvpad.Buffer[T]{Pix: pix, Width: width, Height: height}
+	var result *uvpad.Buffer[T]
+	if copts != nil && (copts.sdf != 0 || copts.msdf != 0) {
+		result = uvpad.SignedDistanceField(src, opts).(*uvpad.Buffer[T])
+	} else {
+		result = alg.Process(src, opts).(*uvpad.Buffer[T])
+	}
+	copy(pix, result.Pix)
+	return nil
+}
+
+// grayMask wraps a coverage buffer of width*height bytes.
+func grayMask(mask *C.uint8_t, width, height int) image.Image {
+	return &image.Gray{
+		Pix:    unsafe.Slice((*uint8)(unsafe.Pointer(mask)), width*height),
+		Stride: width,
+		Rect:   image.Rect(0, 0, width, height),
+	}
+}
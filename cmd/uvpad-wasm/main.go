@@ -0,0 +1,267 @@
+//go:build js && wasm
+
+// Command uvpad-wasm exposes the uvpad dilation to JavaScript. Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o uvpad.wasm ./cmd/uvpad-wasm
+//
+// and load it with the wasm_exec.js shipped in $(go env GOROOT)/lib/wasm.
+// Once running it defines a global uvpad object:
+//
+//	uvpad.dilate(imageData, { algorithm: "jfa", ops: "", bias: 0, colorSpace: "srgb", normalMap: false, normalZ: "keep", open: 0, close: 0, erode: 0, radius: 0, alphaThreshold: 0, keepAlpha: false, composite: false, channels: "rgba", premultiplied: false, edge: "skip", jfaVariant: "jfa", metric: "euclidean", blend: 1, fadeDistance: 0, fade: "gaussian", fadeColor: "#00000000", feather: 0, sdf: false, msdf: false, spread: 8, sdfSign: "inside", sdfChannels: "rgb", background: "transparent", connectivity: 4, kernel: 1, iterations: 32, seed: "alpha", threshold: 0, keyColor: null, keyTolerance: 0, mask: null, invert: false, packed: false, channelMasks: [null, null, null] }) // → ImageData
+//	uvpad.algorithms // → ["gimp", "jfa"]
+//
+// mask is an optional ImageData whose alpha (or luminance, if it is opaque)
+// replaces the alpha channel as the coverage deciding which pixels are
+// dilated. dilate returns a new ImageData and leaves its argument untouched. It returns
+// an Error instead when the options are invalid.
+package main
+
+import (
+	"image"
+	"syscall/js"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+func main() {
+	algorithms := make([]any, 0)
+	for _, name := range uvpad.Algorithms() {
+		algorithms = append(algorithms, name)
+	}
+
+	js.Global().Set("uvpad", map[string]any{
+		"dilate":     js.FuncOf(dilate),
+		"algorithms": algorithms,
+	})
+
+	select {}
+}
+
+func dilate(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return jsError("dilate expects an ImageData argument")
+	}
+	imageData := args[0]
+
+	algorithm := "jfa"
+	var ops []uvpad.Op
+	sdf := false
+	var opts uvpad.Options
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		if v := args[1].Get("algorithm"); v.Type() == js.TypeString {
+			algorithm = v.String()
+		}
+		if v := args[1].Get("ops"); v.Type() == js.TypeString && v.String() != "" {
+			var err error
+			ops, err = uvpad.ParseOps(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+		}
+		if v := args[1].Get("bias"); v.Type() == js.TypeNumber {
+			opts.Bias = v.Float()
+		}
+		if v := args[1].Get("colorSpace"); v.Type() == js.TypeString {
+			colorSpace, err := uvpad.ParseColorSpace(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.ColorSpace = colorSpace
+		}
+		if v := args[1].Get("normalMap"); v.Type() == js.TypeBoolean {
+			opts.NormalMap = v.Bool()
+		}
+		if v := args[1].Get("normalZ"); v.Type() == js.TypeString {
+			normalZ, err := uvpad.ParseNormalZ(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.NormalZ = normalZ
+		}
+		if v := args[1].Get("open"); v.Type() == js.TypeNumber {
+			opts.Open = v.Float()
+		}
+		if v := args[1].Get("close"); v.Type() == js.TypeNumber {
+			opts.Close = v.Float()
+		}
+		if v := args[1].Get("erode"); v.Type() == js.TypeNumber {
+			opts.Erode = v.Float()
+		}
+		if v := args[1].Get("radius"); v.Type() == js.TypeNumber {
+			opts.Radius = v.Float()
+		}
+		if v := args[1].Get("alphaThreshold"); v.Type() == js.TypeNumber {
+			opts.AlphaThreshold = v.Float()
+		}
+		if v := args[1].Get("keepAlpha"); v.Type() == js.TypeBoolean {
+			opts.KeepAlpha = v.Bool()
+		}
+		if v := args[1].Get("channels"); v.Type() == js.TypeString {
+			channels, err := uvpad.ParseChannels(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Channels = channels
+		}
+		if v := args[1].Get("premultiplied"); v.Type() == js.TypeBoolean {
+			opts.Premultiplied = v.Bool()
+		}
+		if v := args[1].Get("composite"); v.Type() == js.TypeBoolean {
+			opts.Composite = v.Bool()
+		}
+		if v := args[1].Get("edge"); v.Type() == js.TypeString {
+			edge, err := uvpad.ParseEdge(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Edge = edge
+		}
+		if v := args[1].Get("jfaVariant"); v.Type() == js.TypeString {
+			variant, err := uvpad.ParseJFAVariant(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.JFA = variant
+		}
+		if v := args[1].Get("blend"); v.Type() == js.TypeNumber {
+			opts.Blend = v.Int()
+		}
+		if v := args[1].Get("fadeDistance"); v.Type() == js.TypeNumber {
+			opts.FadeDistance = v.Float()
+		}
+		if v := args[1].Get("fade"); v.Type() == js.TypeString {
+			falloff, err := uvpad.ParseFalloff(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Fade = falloff
+		}
+		if v := args[1].Get("fadeColor"); v.Type() == js.TypeString {
+			color, err := uvpad.ParseColor(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.FadeColor = color
+		}
+		if v := args[1].Get("connectivity"); v.Type() == js.TypeNumber {
+			opts.Connectivity = v.Int()
+		}
+		if v := args[1].Get("kernel"); v.Type() == js.TypeNumber {
+			opts.Kernel = v.Int()
+		}
+		if v := args[1].Get("iterations"); v.Type() == js.TypeNumber {
+			opts.Iterations = v.Int()
+		}
+		if v := args[1].Get("feather"); v.Type() == js.TypeNumber {
+			opts.Feather = v.Float()
+		}
+		if v := args[1].Get("sdf"); v.Type() == js.TypeBoolean {
+			sdf = v.Bool()
+		}
+		if v := args[1].Get("msdf"); v.Type() == js.TypeBoolean {
+			opts.MSDF = v.Bool()
+			sdf = sdf || opts.MSDF
+		}
+		if v := args[1].Get("spread"); v.Type() == js.TypeNumber {
+			opts.Spread = v.Float()
+		}
+		if v := args[1].Get("sdfSign"); v.Type() == js.TypeString {
+			sign, err := uvpad.ParseSign(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.SDFSign = sign
+		}
+		if v := args[1].Get("sdfChannels"); v.Type() == js.TypeString {
+			channels, err := uvpad.ParseChannels(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.SDFChannels = channels
+		}
+		if v := args[1].Get("background"); v.Type() == js.TypeString {
+			background, err := uvpad.ParseBackground(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Background = background
+		}
+		if v := args[1].Get("seed"); v.Type() == js.TypeString {
+			seed, err := uvpad.ParseSeed(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Seed = seed
+		}
+		if v := args[1].Get("threshold"); v.Type() == js.TypeNumber {
+			opts.Threshold = v.Float()
+		}
+		if v := args[1].Get("keyColor"); v.Type() == js.TypeString {
+			key, err := uvpad.ParseColor(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.KeyColor = &key
+		}
+		if v := args[1].Get("keyTolerance"); v.Type() == js.TypeNumber {
+			opts.KeyTolerance = v.Float()
+		}
+		if v := args[1].Get("invert"); v.Type() == js.TypeBoolean {
+			opts.Invert = v.Bool()
+		}
+		if v := args[1].Get("mask"); v.Type() == js.TypeObject {
+			opts.Mask = imageDataMask(v)
+		}
+		if v := args[1].Get("packed"); v.Type() == js.TypeBoolean {
+			opts.Packed = v.Bool()
+		}
+		if v := args[1].Get("channelMasks"); v.Type() == js.TypeObject {
+			for c := range opts.ChannelMasks {
+				if mask := v.Index(c); mask.Type() == js.TypeObject {
+					opts.ChannelMasks[c] = imageDataMask(mask)
+				}
+			}
+		}
+		if v := args[1].Get("metric"); v.Type() == js.TypeString {
+			metric, err := uvpad.ParseMetric(v.String())
+			if err != nil {
+				return jsError(err.Error())
+			}
+			opts.Metric = metric
+		}
+	}
+
+	alg, err := uvpad.Lookup(algorithm)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	if len(ops) > 0 {
+		alg = uvpad.Pipeline(alg, ops)
+	}
+
+	width, height := imageData.Get("width").Int(), imageData.Get("height").Int()
+	src := uvpad.NewBuffer[uint8](width, height)
+	js.CopyBytesToGo(src.Pix, imageData.Get("data"))
+
+	var result *uvpad.Buffer[uint8]
+	if sdf {
+		result = uvpad.SignedDistanceField(src, opts).(*uvpad.Buffer[uint8])
+	} else {
+		result = alg.Process(src, opts).(*uvpad.Buffer[uint8])
+	}
+
+	data := js.Global().Get("Uint8ClampedArray").New(len(result.Pix))
+	js.CopyBytesToJS(data, result.Pix)
+	return js.Global().Get("ImageData").New(data, width, height)
+}
+
+// imageDataMask copies an ImageData into an image.
+func imageDataMask(v js.Value) image.Image {
+	mask := image.NewNRGBA(image.Rect(0, 0, v.Get("width").Int(), v.Get("height").Int()))
+	js.CopyBytesToGo(mask.Pix, v.Get("data"))
+	return mask
+}
+
+func jsError(message string) js.Value {
+	return js.Global().Get("Error").New("uvpad: " + message)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v3"
+)
+
+// configName is the file name of the configuration file looked up in the
+// current directory and then in the home directory.
+const configName = "uvpad.toml"
+
+// findConfig returns the path of the configuration file to use, or "" if
+// there is none.
+func findConfig(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+
+	candidates := []string{configName}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, configName))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// loadConfig applies the settings of the configuration file as defaults for
+// every flag that wasn't given on the command line. Keys are flag names, and
+// [preset.<name>] tables hold named sets of settings selected with --preset,
+// which take precedence over the top-level ones:
+//
+//	algorithm = "gimp"
+//	suffix = "_dilated"
+//
+//	[preset.lightmaps]
+//	algorithm = "jfa"
+func loadConfig(cmd *cli.Command) error {
+	name := findConfig(cmd.String("config"))
+	preset := cmd.String("preset")
+	if name == "" {
+		if preset != "" {
+			return fmt.Errorf("--preset %s given but no %s found", preset, configName)
+		}
+		return nil
+	}
+
+	var settings map[string]any
+	_, err := toml.DecodeFile(name, &settings)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("config file %s not found", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	presets, ok := settings["preset"].(map[string]any)
+	if _, exists := settings["preset"]; exists && !ok {
+		return fmt.Errorf("%s: preset must be a table of presets", name)
+	}
+	delete(settings, "preset")
+
+	if preset != "" {
+		values, ok := presets[preset].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: unknown preset %q, available: %v", name, preset, slices.Sorted(maps.Keys(presets)))
+		}
+		err := applySettings(cmd, fmt.Sprintf("%s: preset %s", name, preset), values)
+		if err != nil {
+			return err
+		}
+	}
+
+	return applySettings(cmd, name, settings)
+}
+
+// applySettings sets every flag named in settings that wasn't given on the
+// command line.
+func applySettings(cmd *cli.Command, source string, settings map[string]any) error {
+	for key, value := range settings {
+		if key == "config" || key == "preset" || key == "help" || !hasFlag(cmd, key) {
+			return fmt.Errorf("%s: unknown setting %q", source, key)
+		}
+		if cmd.IsSet(key) {
+			continue
+		}
+
+		values := []any{value}
+		if list, ok := value.([]any); ok {
+			values = list
+		}
+		for _, v := range values {
+			s, err := settingString(v)
+			if err != nil {
+				return fmt.Errorf("%s: setting %q: %w", source, key, err)
+			}
+			if err := cmd.Set(key, s); err != nil {
+				return fmt.Errorf("%s: setting %q: %w", source, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func hasFlag(cmd *cli.Command, name string) bool {
+	for _, flag := range cmd.Flags {
+		for _, n := range flag.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func settingString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
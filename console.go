@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// console receives the human-readable progress messages. It is switched to
+// stderr when stdout carries image data.
+var console io.Writer = os.Stdout
+
+// jsonLog replaces the messages with one JSON object per event on the
+// console, for --log-format json.
+var jsonLog bool
+
+var consoleMu sync.Mutex
+
+// level is the verbosity at which a message is shown.
+type level int
+
+const (
+	levelQuiet level = iota
+	levelNormal
+	levelVerbose
+	levelDebug
+)
+
+// verbosity is the highest level of the messages shown, set with --quiet,
+// --verbose and --debug. Errors are always shown.
+var verbosity = levelNormal
+
+// setVerbosity sets the log format and verbosity from --log-format, --quiet,
+// --verbose and --debug.
+func setVerbosity(cmd flagReader) error {
+	jsonLog = cmd.String("log-format") == "json"
+	switch {
+	case cmd.Bool("quiet") && (cmd.Bool("verbose") || cmd.Bool("debug")):
+		return fmt.Errorf("--quiet can't be used with --verbose or --debug")
+	case cmd.Bool("quiet"):
+		verbosity = levelQuiet
+	case cmd.Bool("debug"):
+		verbosity = levelDebug
+	case cmd.Bool("verbose"):
+		verbosity = levelVerbose
+	}
+	return nil
+}
+
+func printf(format string, args ...any) {
+	fmt.Fprintf(consoleWriter{}, format, args...)
+}
+
+// consoleWriter writes to the console above the progress bar.
+type consoleWriter struct{}
+
+func (consoleWriter) Write(p []byte) (int, error) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	shown := bar.erase()
+	n, err := console.Write(p)
+	if shown {
+		bar.draw()
+	}
+	return n, err
+}
+
+// fields are the event specific members of a JSON log event.
+type fields map[string]any
+
+// logEvent reports an event about file, which is empty for events about the
+// whole run, if the verbosity includes lvl. It prints the formatted text
+// message, or with --log-format json an object with the event name, the file
+// and the fields.
+func logEvent(lvl level, event, file string, f fields, format string, args ...any) {
+	if lvl > verbosity {
+		return
+	}
+	if !jsonLog {
+		if format != "" {
+			printf(format, args...)
+		}
+		return
+	}
+
+	object := fields{"time": time.Now().Format(time.RFC3339Nano), "event": event}
+	if file != "" {
+		object["file"] = file
+	}
+	for k, v := range f {
+		object[k] = v
+	}
+	line, err := json.Marshal(object)
+	if err != nil {
+		panic(err)
+	}
+
+	printf("%s\n", line)
+}
+
+// logError reports an error about file, or about the whole run when file is
+// empty. Text errors go to stderr.
+func logError(file string, err error) {
+	if jsonLog {
+		logEvent(levelQuiet, "error", file, fields{"error": err.Error()}, "")
+		return
+	}
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	shown := bar.erase()
+	if file != "" {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+	} else {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if shown {
+		bar.draw()
+	}
+}
+
+// progressLogger returns an uvpad.Options.Progress callback that logs a
+// progress event for file each time it advances by a percent.
+func progressLogger(file string) func(float64) {
+	last := -1
+	return func(fraction float64) {
+		percent := int(fraction * 100)
+		if percent == last {
+			return
+		}
+		last = percent
+		logEvent(levelNormal, "progress", file, fields{"progress": fraction}, "")
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/urfave/cli/v3"
+)
+
+// daemonCommand dilates raw buffers sent over a Unix socket, so editor
+// plugins pay neither for starting a process nor for encoding images.
+var daemonCommand = &cli.Command{
+	Name:  "daemon",
+	Usage: "Dilate raw RGBA buffers sent over a Unix socket, for editor plugins that pad in-memory textures without starting a process per image",
+	Description: "Every request is a line of JSON followed by the samples of the image, such as " +
+		"{\"width\": 1024, \"height\": 1024, \"depth\": \"8\", \"settings\": {\"radius\": 8}}, " +
+		"where depth is 8, 16 or float and the samples are RGBA, row by row, in the byte order of the machine like .raw images. " +
+		"Settings are named like the flags, with the other flags as defaults. " +
+		"The answer is a line of JSON with the width, height and depth of the result followed by its samples, " +
+		"or with an error and nothing else. A connection can send any number of requests, one after the other.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:      "socket",
+			Value:     filepath.Join(os.TempDir(), "uvpad.sock"),
+			Usage:     "Path of the Unix socket the daemon listens on, only accessible by the current user",
+			TakesFile: true,
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.NArg() != 0 {
+			return fmt.Errorf("daemon takes no arguments, usage: uvpad daemon [--socket path]")
+		}
+		if err := loadConfig(cmd.Root()); err != nil {
+			return err
+		}
+		limitThreads(cmd)
+		if err := setVerbosity(cmd); err != nil {
+			return err
+		}
+
+		budget, _ := parseSize(cmd.String("max-memory"))
+		if budget == 0 {
+			budget = availableMemory()
+		}
+		d := &daemon{cmd: cmd, budget: newMemoryBudget(budget), maxMemory: budget}
+
+		path := cmd.String("socket")
+		// A socket left behind by a daemon that didn't exit cleanly.
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to listen on %s: %w", path, err))
+		}
+		defer os.Remove(path)
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return withExitCode(exitWrite, fmt.Errorf("failed to restrict access to %s: %w", path, err))
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			listener.Close()
+		}()
+
+		logEvent(levelNormal, "listening", "", fields{"socket": path}, "Listening on %s\n", path)
+		var wg sync.WaitGroup
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return withExitCode(exitRead, fmt.Errorf("failed to accept a connection: %w", err))
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.serve(ctx, conn)
+			}()
+		}
+		// Requests being dilated are finished and answered before exiting.
+		wg.Wait()
+		return nil
+	},
+}
+
+// daemonRequest is the header of a request to the daemon.
+type daemonRequest struct {
+	// Name names the image in the log.
+	Name     string         `json:"name"`
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	Depth    string         `json:"depth"`
+	Settings map[string]any `json:"settings"`
+}
+
+// daemonResponse is the header of an answer of the daemon.
+type daemonResponse struct {
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Depth  string `json:"depth,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// daemon dilates the requests of every connection with the settings of the
+// command line, overridden by those of the request, within a memory budget
+// shared by the requests being served.
+type daemon struct {
+	cmd       *cli.Command
+	budget    *memoryBudget
+	maxMemory int64
+}
+
+// serve answers the requests of conn until it is closed, the daemon stops or
+// a request can't be read.
+func (d *daemon) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	// Unblock the read of the next request when the daemon stops.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for ctx.Err() == nil {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req daemonRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			err = fmt.Errorf("failed to parse the request: %w", err)
+			d.answer(w, nil, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			d.answer(w, nil, err)
+			return
+		}
+		// The samples are read within the budget, and accounted for by the
+		// estimate of the dilation once read.
+		size := int64(req.Width) * int64(req.Height) * 4 * int64(rawSampleSizes[req.Depth])
+		if d.maxMemory > 0 && size > d.maxMemory {
+			d.answer(w, nil, fmt.Errorf("the samples of %s take %s, more than the memory budget of %s", req.Name, formatSize(size), formatSize(d.maxMemory)))
+			return
+		}
+		d.budget.acquire(size)
+		data := make([]byte, size)
+		_, err = io.ReadFull(r, data)
+		d.budget.release(size)
+		if err != nil {
+			return
+		}
+
+		start := time.Now()
+		result, err := d.dilate(req, data)
+		if err := d.answer(w, result, err); err != nil {
+			return
+		}
+		if err != nil {
+			logError(req.Name, err)
+			continue
+		}
+		logEvent(levelNormal, "request", req.Name, fields{
+			"width":   req.Width,
+			"height":  req.Height,
+			"seconds": time.Since(start).Seconds(),
+		}, "Dilated %s (%dx%d) in %v\n", req.Name, req.Width, req.Height, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// validate checks the header before the samples that follow it are read.
+func (req *daemonRequest) validate() error {
+	if req.Width <= 0 || req.Height <= 0 {
+		return fmt.Errorf("width and height must be positive, got %dx%d", req.Width, req.Height)
+	}
+	if _, ok := rawSampleSizes[req.Depth]; !ok {
+		return fmt.Errorf("depth must be 8, 16 or float, got %q", req.Depth)
+	}
+	if int64(req.Width)*int64(req.Height) > 1<<30 {
+		return fmt.Errorf("%dx%d images are too large", req.Width, req.Height)
+	}
+	if req.Name == "" {
+		req.Name = "buffer"
+	}
+	return nil
+}
+
+// dilate pads the samples of a request and returns the result.
+func (d *daemon) dilate(req daemonRequest, data []byte) (image.Image, error) {
+	values := map[string]any{}
+	for name, value := range req.Settings {
+		if slices.Contains(serverFlags, name) {
+			return nil, fmt.Errorf("%q can't be set by a request", name)
+		}
+		v, err := validateOverride(d.cmd.Root(), name, value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
+	}
+	j := job{input: req.Name, settings: newSettings(overrides{d.cmd, values})}
+	j.budget, j.maxMemory = d.budget, d.maxMemory
+
+	rect := image.Rect(0, 0, req.Width, req.Height)
+	config := image.Config{ColorModel: color.NRGBAModel, Width: req.Width, Height: req.Height}
+	decode := func() (image.Image, string, error) {
+		return &image.NRGBA{Pix: data, Stride: req.Width * 4, Rect: rect}, "", nil
+	}
+	switch req.Depth {
+	case "16":
+		config.ColorModel = color.NRGBA64Model
+		decode = func() (image.Image, string, error) {
+			// image.NRGBA64 holds big endian samples.
+			img := image.NewNRGBA64(rect)
+			for i, v := range unsafe.Slice((*uint16)(unsafe.Pointer(unsafe.SliceData(data))), len(data)/2) {
+				img.Pix[i*2], img.Pix[i*2+1] = uint8(v>>8), uint8(v)
+			}
+			return img, "", nil
+		}
+	case "float":
+		config.ColorModel = uvpad.FloatModel
+		decode = func() (image.Image, string, error) {
+			b := rawBuffer(data, req.Width, req.Height, req.Depth).(*uvpad.Buffer[float32])
+			return uvpad.FloatImage{Buffer: b}, "", nil
+		}
+	}
+
+	var result image.Image
+	err := padImage(j, req.Name, config, decode, func(img image.Image, _ string) error {
+		result = img
+		return nil
+	})
+	return result, err
+}
+
+// answer writes the answer to a request: the header and samples of result,
+// or err.
+func (d *daemon) answer(w *bufio.Writer, result image.Image, err error) error {
+	var resp daemonResponse
+	var samples []byte
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		samples, resp.Depth = rawSamples(result)
+		resp.Width, resp.Height = result.Bounds().Dx(), result.Bounds().Dy()
+	}
+
+	header, _ := json.Marshal(resp)
+	w.Write(append(header, '\n'))
+	w.Write(samples)
+	return w.Flush()
+}
+
+// rawSamples returns the samples of img in the byte order of the machine and
+// their depth, as .raw images store them.
+func rawSamples(img image.Image) ([]byte, string) {
+	var b uvpad.Image
+	if f, ok := img.(uvpad.FloatImage); ok {
+		b = f.Buffer
+	} else {
+		b = uvpad.FromImage(img)
+	}
+	switch b := b.(type) {
+	case *uvpad.Buffer[uint16]:
+		return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(b.Pix))), len(b.Pix)*2), "16"
+	case *uvpad.Buffer[float32]:
+		return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(b.Pix))), len(b.Pix)*4), "float"
+	default:
+		return b.(*uvpad.Buffer[uint8]).Pix, "8"
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "errors"
+
+// Exit codes of the process, documented in the README.
+const (
+	exitOK     = 0
+	exitRead   = 1 // an input can't be read or decoded
+	exitWrite  = 2 // an output can't be encoded or written
+	exitUsage  = 3 // invalid arguments, configuration or manifest
+	exitVerify = 4 // an input failed a check, such as --no-alpha error or --max-memory
+)
+
+// exitError is an error that ends the process with a specific exit code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode attaches an exit code to err. A nil err stays nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code, err}
+}
+
+// exitCode returns the exit code for err. Errors without one come from
+// parsing the command line or validating the arguments.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitUsage
+}
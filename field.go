@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// fieldFlags name the images derived from the nearest seeds of the dilation,
+// and the island map, which are written for a single input only.
+var fieldFlags = []string{"debug-voronoi", "debug-distance", "offset-map", "export-distance", "island-map"}
+
+// fieldOutputs are the paths of the requested images derived from the
+// nearest seeds, empty for those not requested.
+type fieldOutputs struct {
+	voronoi, distance, offsets string
+
+	// export receives the raw distances, and the nearest seeds with
+	// exportNearest.
+	export        string
+	exportNearest bool
+}
+
+func (d fieldOutputs) requested() bool {
+	return d.voronoi != "" || d.distance != "" || d.offsets != "" || d.export != ""
+}
+
+// writeFieldOutputs writes the requested images derived from the nearest
+// seeds of the dilation of src.
+func writeFieldOutputs(d fieldOutputs, alg uvpad.Algorithm, src uvpad.Image, opts uvpad.Options) error {
+	// The dilation has already reported its progress.
+	opts.Progress, opts.Log = nil, nil
+	field := uvpad.NearestSeeds(alg, src, opts)
+
+	if d.voronoi != "" {
+		if _, err := save(d.voronoi, field.Voronoi().Image(), ""); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save Voronoi image: %w", err))
+		}
+	}
+	if d.distance != "" {
+		// With a radius, full scale marks the pixels at the radius.
+		if _, err := save(d.distance, field.Heatmap(opts.Radius).Image(), ""); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save distance heatmap: %w", err))
+		}
+	}
+	if d.offsets != "" {
+		if _, err := save(d.offsets, field.OffsetMap().Image(), ""); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save offset map: %w", err))
+		}
+	}
+	if d.export != "" {
+		err := writeAtomic(d.export, func(w io.Writer) error {
+			if isEXR(d.export) {
+				return field.WriteEXR(w, d.exportNearest)
+			}
+			return field.WriteRaw(w, d.exportNearest)
+		})
+		if err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to export distances: %w", err))
+		}
+	}
+	return nil
+}
+
+// isEXR reports whether distances exported to path are written as OpenEXR
+// rather than raw floats.
+func isEXR(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".exr")
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// openInput opens an input file, or reads all of stdin for "-" so that the
+// result can be rewound after sniffing the header.
+func openInput(input string) (io.ReadSeekCloser, error) {
+	if input != "-" {
+		return os.Open(input)
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
+// save encodes the image to output, or to stdout for "-", and returns the hex
+// SHA-256 digest of the written data, computed while encoding. The format is
+// derived from the file extension unless given explicitly.
+func save(output string, data image.Image, format string) (string, error) {
+	if format == "" {
+		format = uvpad.FormatForPath(output)
+	}
+	if format == "" {
+		format = "png"
+	}
+
+	hash := sha256.New()
+	encode := func(w io.Writer) error {
+		err := uvpad.Encode(io.MultiWriter(w, hash), data, format)
+		if err != nil {
+			return fmt.Errorf("failed to encode output image: %w", err)
+		}
+		return nil
+	}
+
+	var err error
+	if output == "-" {
+		err = encode(os.Stdout)
+	} else {
+		err = writeAtomic(output, encode)
+	}
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// formatName returns the name of the format of a --format value, which is
+// either the name or an extension of the format, such as jpg, or "" if no
+// registered format matches.
+func formatName(format string) string {
+	return uvpad.FormatForPath("." + format)
+}
+
+// writeAtomic writes a file through a temporary file in the same directory
+// that is renamed over the destination once complete, so that a crash never
+// leaves a partially written file behind.
+func writeAtomic(name string, write func(io.Writer) error) error {
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to replace output file: %w", err)
+	}
+	return nil
+}
+
+// backup copies a file to name+".bak", replacing any previous backup.
+func backup(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to back up input file: %w", err)
+	}
+	defer src.Close()
+
+	err = writeAtomic(name+".bak", func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up input file: %w", err)
+	}
+	return nil
+}
+
+// sameFile reports whether two paths refer to the same existing file.
+func sameFile(a, b string) bool {
+	if a == "-" || b == "-" {
+		return false
+	}
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
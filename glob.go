@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// input is an input file together with its path relative to the directory
+// or glob root it was found in, which --output-dir mirrors.
+type input struct {
+	path, rel string
+}
+
+// expandInputs expands glob patterns among the input arguments, so that
+// patterns work even where the shell doesn't expand them (Windows). A "**"
+// segment matches any number of directories. With recursive set, directories
+// are replaced by every supported image below them. Files found either way
+// that are named like outputs, with suffix, are skipped, so that outputs of an
+// earlier run aren't padded again. Other arguments are passed through
+// unchanged.
+func expandInputs(args []string, recursive bool, suffix string) ([]input, error) {
+	var inputs []input
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			if !recursive {
+				return nil, fmt.Errorf("%s is a directory, use --recursive to process it", arg)
+			}
+			images, err := walkImages(arg, suffix)
+			if err != nil {
+				return nil, withExitCode(exitRead, err)
+			}
+			inputs = append(inputs, images...)
+			continue
+		}
+
+		if !hasMeta(arg) {
+			rel := arg
+			if !filepath.IsLocal(arg) {
+				rel = filepath.Base(arg)
+			}
+			inputs = append(inputs, input{arg, rel})
+			continue
+		}
+
+		matches, err := glob(arg, suffix)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", arg)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// glob returns the files matching pattern in lexical order, but for outputs
+// named with suffix.
+func glob(pattern, suffix string) ([]input, error) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	// Walk from the longest prefix without metacharacters.
+	base := 0
+	for base < len(segments)-1 && !hasMeta(segments[base]) {
+		base++
+	}
+	root := strings.Join(segments[:base], "/")
+	if root == "" && strings.HasPrefix(pattern, "/") {
+		root = "/"
+	}
+	walkRoot := root
+	if walkRoot == "" {
+		walkRoot = "."
+	}
+	rest := segments[base:]
+
+	for _, segment := range rest {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var matches []input
+	err := filepath.WalkDir(filepath.FromSlash(walkRoot), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == filepath.FromSlash(walkRoot) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(walkRoot), p)
+		if err != nil {
+			return err
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) && !skipOutput(p, suffix) {
+			matches = append(matches, input{p, rel})
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches zero or more whole segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// walkImages returns every file below dir that decodes as a supported image,
+// warning about and skipping the others, but for outputs named with suffix.
+func walkImages(dir, suffix string) ([]input, error) {
+	var images []input
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || skipOutput(p, suffix) {
+			return nil
+		}
+
+		if !isSupportedImage(p) && archiveExt(p) == "" {
+			if jsonLog {
+				logEvent(levelNormal, "unsupported", p, nil, "")
+			} else if verbosity >= levelNormal {
+				fmt.Fprintf(os.Stderr, "Skipping %s: unsupported file\n", p)
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		images = append(images, input{p, rel})
+		return nil
+	})
+	return images, err
+}
+
+// skipOutput reports whether name is named like the output of an input, with
+// suffix before the extension, logging that it's skipped.
+func skipOutput(name, suffix string) bool {
+	if suffix == "" {
+		return false
+	}
+	ext := archiveExt(name)
+	if ext == "" {
+		ext = path.Ext(name)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(name, ext), suffix) {
+		return false
+	}
+	logEvent(levelVerbose, "skipped", name, nil, "Skipping %s: named like an output with --suffix %q\n", name, suffix)
+	return true
+}
+
+// isSupportedImage reports whether the file's header is recognized by one of
+// the registered decoders.
+func isSupportedImage(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	_, _, err = uvpad.DecodeConfig(f)
+	return err == nil
+}
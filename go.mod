@@ -2,4 +2,16 @@ module github.com/meir/uvpad
 
 go 1.23.4
 
-require github.com/urfave/cli/v3 v3.0.0-beta1
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/urfave/cli/v3 v3.0.0-beta1
+	google.golang.org/grpc v1.75.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+)
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/meir/uvpad/proto/uvpadv1"
+	"github.com/urfave/cli/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/meir/uvpad --go-grpc_out=. --go-grpc_opt=module=github.com/meir/uvpad proto/uvpad.proto
+
+// grpcChunkSize is the size of the chunks results are streamed back in, well
+// below the 4 MiB messages are limited to.
+const grpcChunkSize = 1 << 20
+
+// newGRPCServer returns a gRPC server of the service of proto/uvpad.proto,
+// dilating with the settings and memory budget of srv.
+func newGRPCServer(srv *server) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			start := time.Now()
+			resp, err := handler(ctx, req)
+			logCall(ctx, info.FullMethod, start, err)
+			return resp, err
+		}),
+		grpc.StreamInterceptor(func(service any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			start := time.Now()
+			err := handler(service, stream)
+			logCall(stream.Context(), info.FullMethod, start, err)
+			return err
+		}),
+	)
+	uvpadv1.RegisterUvpadServer(s, &grpcService{srv: srv})
+	return s
+}
+
+// logCall logs a gRPC call like the HTTP server logs requests.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	code := status.Code(err)
+	logEvent(levelNormal, "request", "", fields{
+		"method":  method,
+		"remote":  remote,
+		"code":    code.String(),
+		"seconds": time.Since(start).Seconds(),
+	}, "%s from %s: %s in %v\n", method, remote, code, time.Since(start).Round(time.Millisecond))
+}
+
+// grpcService implements the service of proto/uvpad.proto like the HTTP
+// server answers requests.
+type grpcService struct {
+	uvpadv1.UnimplementedUvpadServer
+	srv *server
+}
+
+type imageStream = grpc.BidiStreamingServer[uvpadv1.ImageRequest, uvpadv1.ImageChunk]
+
+func (g *grpcService) Dilate(stream imageStream) error {
+	return g.pad(stream, false)
+}
+
+func (g *grpcService) DistanceField(stream imageStream) error {
+	return g.pad(stream, true)
+}
+
+func (g *grpcService) Info(context.Context, *uvpadv1.InfoRequest) (*uvpadv1.InfoResponse, error) {
+	resp := &uvpadv1.InfoResponse{
+		Algorithms: uvpad.Algorithms(),
+		Formats:    uvpad.Formats(),
+	}
+	for _, flag := range g.srv.cmd.Root().Flags {
+		name := flag.Names()[0]
+		if name == "help" || slices.Contains(batchFlags, name) || slices.Contains(serverFlags, name) {
+			continue
+		}
+		setting := &uvpadv1.Setting{Name: name, Default: fmt.Sprint(g.srv.cmd.Value(name))}
+		if f, ok := flag.(cli.DocGenerationFlag); ok {
+			setting.Usage = f.GetUsage()
+		}
+		resp.Settings = append(resp.Settings, setting)
+	}
+	return resp, nil
+}
+
+// pad answers a stream of Dilate, or of DistanceField if sdf is set, with
+// the encoded result in chunks.
+func (g *grpcService) pad(stream imageStream, sdf bool) error {
+	dir, err := os.MkdirTemp("", "uvpad-grpc")
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to store the uploaded files: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	values := map[string]any{}
+	var data []byte
+	var size int64
+	uploads := map[string]*os.File{}
+	defer func() {
+		for _, f := range uploads {
+			f.Close()
+		}
+	}()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch payload := req.Payload.(type) {
+		case *uvpadv1.ImageRequest_Settings:
+			for name, value := range payload.Settings.GetValues() {
+				if err := g.srv.setParam(values, name, value); err != nil {
+					return status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+		case *uvpadv1.ImageRequest_Upload:
+			upload := payload.Upload
+			if !slices.Contains(fileFlags, upload.Setting) {
+				return status.Errorf(codes.InvalidArgument, "%q isn't a setting naming a file, expected one of %v", upload.Setting, fileFlags)
+			}
+			f, ok := uploads[upload.Setting]
+			if !ok {
+				// The extension tells the format of meshes and atlases.
+				path := filepath.Join(dir, upload.Setting+filepath.Ext(upload.Name))
+				if f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); err != nil {
+					return status.Errorf(codes.Internal, "failed to store %q: %v", upload.Setting, err)
+				}
+				uploads[upload.Setting] = f
+				values[upload.Setting] = path
+			}
+			if _, err := f.Write(upload.Data); err != nil {
+				return status.Errorf(codes.Internal, "failed to store %q: %v", upload.Setting, err)
+			}
+			size += int64(len(upload.Data))
+		case *uvpadv1.ImageRequest_Image:
+			data = append(data, payload.Image...)
+			size += int64(len(payload.Image))
+		}
+		if size > g.srv.maxUpload {
+			return status.Errorf(codes.ResourceExhausted, "request larger than --max-upload (%d bytes)", g.srv.maxUpload)
+		}
+	}
+	if len(data) == 0 {
+		return status.Error(codes.InvalidArgument, "no image, stream it in the image field of the requests")
+	}
+	for _, f := range uploads {
+		if err := f.Close(); err != nil {
+			return status.Errorf(codes.Internal, "failed to store the uploaded files: %v", err)
+		}
+	}
+	if sdf {
+		values["sdf"] = true
+	}
+
+	padded, format, err := g.srv.dilate("request", data, values)
+	if err != nil {
+		if errors.Is(err, errOverBudget) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		switch exitCode(err) {
+		case exitRead, exitUsage:
+			return status.Error(codes.InvalidArgument, err.Error())
+		case exitVerify:
+			return status.Error(codes.FailedPrecondition, err.Error())
+		default:
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	// The first chunk names the format.
+	for first := true; first || len(padded) > 0; first = false {
+		n := min(len(padded), grpcChunkSize)
+		chunk := &uvpadv1.ImageChunk{Data: padded[:n]}
+		if first {
+			chunk.Format = format
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		padded = padded[n:]
+	}
+	return nil
+}
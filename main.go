@@ -4,330 +4,1220 @@ import (
 	"context"
 	"fmt"
 	"image"
-	"image/color"
-	"image/png"
-	"math"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
-	"sync"
-	"time"
 
+	"github.com/meir/uvpad/pkg/uvpad"
 	"github.com/urfave/cli/v3"
 )
 
 func main() {
-	(&cli.Command{
-		Name:  "uvpad",
-		Usage: "Texture dilating tool",
+	err := (&cli.Command{
+		Name:      "uvpad",
+		Usage:     "Texture dilating tool",
+		ArgsUsage: "<input image, glob or directory>...",
+		Commands:  []*cli.Command{maskCommand, benchCommand, serveCommand, daemonCommand},
 		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:      "config",
+				Value:     "",
+				Usage:     "Configuration file, by default uvpad.toml in the current or home directory",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:  "preset",
+				Value: "",
+				Usage: "Apply the named [preset.<name>] settings from the configuration file",
+			},
 			&cli.StringFlag{
 				Name:  "output",
 				Value: "",
-				Usage: "Output image file",
+				Usage: "Output image file, - for stdout",
+			},
+			&cli.StringFlag{
+				Name:  "debug-voronoi",
+				Usage: "Also write an image coloring every pixel by its nearest opaque pixel, to see where dilated colors come from",
+			},
+			&cli.StringFlag{
+				Name:  "debug-distance",
+				Usage: "Also write a heatmap of the distance from every pixel to its nearest opaque pixel, up to --radius or the largest distance",
+			},
+			&cli.StringFlag{
+				Name:  "export-distance",
+				Usage: "Also write the distance from every pixel to its nearest opaque pixel as floats: an OpenEXR Y channel (.exr) or raw little endian float32 (.bin)",
+				Validator: func(path string) error {
+					if ext := strings.ToLower(filepath.Ext(path)); ext != ".exr" && ext != ".bin" {
+						return fmt.Errorf("--export-distance must be an .exr or .bin file, got %q", path)
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "export-nearest",
+				Value: false,
+				Usage: "Also export the index y*width+x of the nearest opaque pixel with --export-distance, -1 where there is none",
+			},
+			&cli.StringFlag{
+				Name:  "offset-map",
+				Usage: "Also write a 16-bit image whose red and green hold the offset from every pixel to its nearest opaque pixel, biased by 32768",
+			},
+			&cli.BoolFlag{
+				Name:  "in-place",
+				Value: false,
+				Usage: "Overwrite the input files, keeping a .bak copy of each",
+			},
+			&cli.BoolFlag{
+				Name:  "no-backup",
+				Value: false,
+				Usage: "Don't create .bak copies with --in-place",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "",
+				Usage: "Output image format (png, jpeg or jpg, exr), by default derived from the output file extension",
+				Validator: func(format string) error {
+					if format != "" && formatName(format) == "" {
+						return fmt.Errorf("--format must be one of %s, got %q", strings.Join(uvpad.Formats(), ", "), format)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "raw-size",
+				Usage: "Size of .raw inputs, headerless RGBA samples that are memory-mapped and dilated in --tile tiles, such as 16384x16384",
+				Validator: func(s string) error {
+					_, _, err := parseDimensions(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "raw-depth",
+				Value: "8",
+				Usage: "Sample depth of .raw inputs and outputs: 8, 16 or float, in the byte order of the machine",
+				Validator: func(depth string) error {
+					if _, ok := rawSampleSizes[depth]; !ok {
+						return fmt.Errorf("--raw-depth must be 8, 16 or float, got %q", depth)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "suffix",
+				Value: "_padded",
+				Usage: "Suffix added to input file names to name the outputs",
+			},
+			&cli.StringFlag{
+				Name:  "output-dir",
+				Value: "",
+				Usage: "Write outputs into this directory, mirroring the inputs' directory structure",
+			},
+			&cli.StringFlag{
+				Name:  "algorithm",
+				Value: "jfa",
+				Usage: "Dilation algorithm: " + strings.Join(uvpad.Algorithms(), ", ") + "; auto picks gimp, jfa or pushpull per image by its holes and coverage",
+				Validator: func(name string) error {
+					_, err := uvpad.Lookup(name)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "jfa-variant",
+				Value: "jfa",
+				Usage: "Extra jump flood passes for accuracy: jfa, 1+jfa, jfa+1, jfa+2, 1+jfa+1 or 1+jfa+2; hjfa in place of jfa floods a half resolution copy first, faster on large images but less accurate",
+				Validator: func(name string) error {
+					_, err := uvpad.ParseJFAVariant(name)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "metric",
+				Value: "euclidean",
+				Usage: "Distance used to find the nearest opaque pixel (jfa, edt): euclidean, manhattan or chebyshev",
+				Validator: func(name string) error {
+					_, err := uvpad.ParseMetric(name)
+					return err
+				},
+			},
+			&cli.IntFlag{
+				Name:  "blend",
+				Value: 1,
+				Usage: "Blend the colors of this many nearest opaque pixels weighted by inverse distance (jfa, edt), 1 copies the nearest",
+				Validator: func(k int64) error {
+					if k < 1 || k > 16 {
+						return fmt.Errorf("--blend must be in the range [1, 16], got %d", k)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "feather",
+				Value: 0,
+				Usage: "Ramp the alpha down to transparent over the last this many pixels before --radius (or from the opaque areas without it)",
+				Validator: func(feather float64) error {
+					if feather < 0 {
+						return fmt.Errorf("--feather must not be negative, got %v", feather)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "background",
+				Value: "transparent",
+				Usage: "Fill of the pixels beyond --radius or without any opaque pixel: transparent, average or color:#RRGGBB",
+				Validator: func(background string) error {
+					_, err := uvpad.ParseBackground(background)
+					return err
+				},
+			},
+			&cli.IntFlag{
+				Name:  "connectivity",
+				Value: 4,
+				Usage: "Neighbours averaged by the gimp algorithm: 4 along the axes, or 8 including diagonals",
+				Validator: func(n int64) error {
+					if n != 4 && n != 8 {
+						return fmt.Errorf("--connectivity must be 4 or 8, got %d", n)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:  "kernel",
+				Value: 1,
+				Usage: "Radius of the neighbourhood of the gimp algorithm, larger needs fewer passes",
+				Validator: func(n int64) error {
+					if n < 1 || n > 16 {
+						return fmt.Errorf("--kernel must be in the range [1, 16], got %d", n)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:  "iterations",
+				Value: uvpad.DefaultIterations,
+				Usage: "Relaxation iterations per pyramid level of the diffusion algorithm, more is smoother but slower",
+				Validator: func(n int64) error {
+					if n < 1 {
+						return fmt.Errorf("--iterations must be at least 1, got %d", n)
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:   "slower",
+				Value:  false,
+				Usage:  "Deprecated: use --algorithm gimp",
+				Hidden: true,
+			},
+			&cli.StringFlag{
+				Name:  "ops",
+				Usage: "Pipeline of operations run in one pass, such as erode:1,dilate:16,blur:2 (dilate, erode, open, close, blur)",
+				Validator: func(ops string) error {
+					_, err := uvpad.ParseOps(ops)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "sdf",
+				Value: false,
+				Usage: "Output the signed distance field of the coverage instead of dilating it",
+			},
+			&cli.BoolFlag{
+				Name:  "msdf",
+				Value: false,
+				Usage: "Output a multi-channel signed distance field, whose median keeps corners sharp (implies --sdf)",
+			},
+			&cli.FloatFlag{
+				Name:  "spread",
+				Value: uvpad.DefaultSpread,
+				Usage: "Distance in pixels from the edge to black and white in the --sdf output",
+				Validator: func(spread float64) error {
+					if spread <= 0 {
+						return fmt.Errorf("--spread must be positive, got %v", spread)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "sdf-sign",
+				Value: "inside",
+				Usage: "Side of the edge above 0.5 in the --sdf output: inside or outside",
+				Validator: func(s string) error {
+					_, err := uvpad.ParseSign(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "sdf-channels",
+				Value: "rgb",
+				Usage: "Channels of the --sdf output holding the field, such as a; free color channels hold the dilated colors and alpha is otherwise opaque",
+				Validator: func(s string) error {
+					_, err := uvpad.ParseChannels(s)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "sdf-depth",
+				Value: "",
+				Usage: "Sample depth of the --sdf output: " + strings.Join(sdfDepths, ", ") + ", by default that of the input; float keeps distances beyond the spread in exr outputs",
+				Validator: func(s string) error {
+					if s != "" && !slices.Contains(sdfDepths, s) {
+						return fmt.Errorf("unknown --sdf-depth %q, expected %s", s, strings.Join(sdfDepths, ", "))
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "mask",
+				Usage: "Coverage image deciding which pixels are dilated instead of the alpha channel: its alpha, or its luminance if it is opaque",
+			},
+			&cli.StringFlag{
+				Name:  "mesh",
+				Usage: "OBJ, glTF (.gltf, .glb) or binary FBX model whose UV triangles, rasterized, decide which pixels are dilated instead of the alpha channel or --mask, for fully opaque bakes",
+			},
+			&cli.BoolFlag{
+				Name:  "per-island",
+				Value: false,
+				Usage: "Dilate every UV island of --mesh on its own, so colors never cross into the gutter of another island",
+			},
+			&cli.BoolFlag{
+				Name:  "stitch-seams",
+				Value: false,
+				Usage: "Average the colors on both sides of the UV seams of --mesh before dilating, so edges split between charts match",
+			},
+			&cli.StringFlag{
+				Name:  "islands",
+				Usage: "Island map image, a color per UV island and black or transparent elsewhere, whose islands are dilated on their own like --per-island",
+			},
+			&cli.StringFlag{
+				Name:  "atlas",
+				Usage: "JSON (TexturePacker or x, y, w, h objects) or CSV (x, y, w, h) of atlas rectangles that are dilated independently",
+			},
+			&cli.StringFlag{
+				Name:  "grid",
+				Usage: "Dilate the cells of a uniform sprite sheet of this many columns and rows, such as 8x4, independently like --atlas rectangles",
+				Validator: func(s string) error {
+					_, _, err := uvpad.ParseGrid(s)
+					return err
+				},
+			},
+			&cli.IntFlag{
+				Name:  "grid-padding",
+				Value: 0,
+				Usage: "Pixels between the cells of --grid, filled as --gutter says",
+				Validator: func(padding int64) error {
+					if padding < 0 {
+						return fmt.Errorf("--grid-padding must not be negative, got %d", padding)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "gutter",
+				Value: "dilate",
+				Usage: "Fill of the pixels outside every island or --atlas rectangle: dilate the nearest one into them, keep them, or clamp to copy the nearest edge",
+				Validator: func(s string) error {
+					_, err := uvpad.ParseGutter(s)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "mesh-report",
+				Value: false,
+				Usage: "Report the texel density of every UV island of --mesh, the UV coverage and the smallest gap between islands, warning when it is below the radius",
+			},
+			&cli.BoolFlag{
+				Name:  "debug-wireframe",
+				Value: false,
+				Usage: "Also write the padded image with the UV wireframe of --mesh drawn over it, next to the output with a _debug suffix",
+			},
+			&cli.StringFlag{
+				Name:  "wireframe-color",
+				Value: "#00ff00",
+				Usage: "Color of the lines of --debug-wireframe (#RRGGBB or #RRGGBBAA)",
+				Validator: func(color string) error {
+					_, err := uvpad.ParseColor(color)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "island-map",
+				Usage: "Also write a 16-bit image of the UV island of every pixel, from --mesh, --islands, --atlas or --grid: 0 outside every island, its ID from 1 inside, gutters included unless --gutter keeps them",
+			},
+			&cli.StringFlag{
+				Name:  "mesh-object",
+				Usage: "Name or index of the mesh of --mesh to rasterize, an object (o) in OBJ models and a model in FBX, by default all of them",
+			},
+			&cli.StringFlag{
+				Name:  "mesh-primitive",
+				Usage: "Index of the primitive of the glTF meshes of --mesh to rasterize, by default all of them",
+			},
+			&cli.StringFlag{
+				Name:  "uv-set",
+				Usage: "Name or index of the UV set of --mesh to use, such as 1 or TEXCOORD_1 in glTF or a UV layer name in FBX, by default the first",
+			},
+			&cli.StringFlag{
+				Name:  "mesh-material",
+				Usage: "Name or index of the material whose faces of --mesh are rasterized, so the mask matches the texture, by default all of them",
+			},
+			&cli.StringFlag{
+				Name:  "seed",
+				Value: "alpha",
+				Usage: "Coverage deciding which pixels are dilated: alpha, or luminance above --threshold for grayscale bakes where black is empty",
+				Validator: func(seed string) error {
+					_, err := uvpad.ParseSeed(seed)
+					return err
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "threshold",
+				Value: 0,
+				Usage: "Luminance, from 0 to 1, above which pixels are opaque with --seed luminance",
+				Validator: func(threshold float64) error {
+					if threshold < 0 || threshold > 1 {
+						return fmt.Errorf("--threshold must be between 0 and 1, got %v", threshold)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "key-color",
+				Usage: "Seed images without an alpha channel by this background color (#RRGGBB): matching pixels are dilated into, every other pixel is opaque",
+				Validator: func(color string) error {
+					_, err := uvpad.ParseColor(color)
+					return err
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "key-tolerance",
+				Value: 0,
+				Usage: "Maximum difference per channel, from 0 to 1, of the pixels matching --key-color",
+				Validator: func(tolerance float64) error {
+					if tolerance < 0 || tolerance > 1 {
+						return fmt.Errorf("--key-tolerance must be between 0 and 1, got %v", tolerance)
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "packed",
+				Value: false,
+				Usage: "Treat the color channels as independent data (e.g. ORM), dilating channels with a --mask-r/g/b separately",
+			},
+			&cli.StringFlag{
+				Name:  "mask-r",
+				Usage: "Coverage image of the red channel with --packed",
+			},
+			&cli.StringFlag{
+				Name:  "mask-g",
+				Usage: "Coverage image of the green channel with --packed",
+			},
+			&cli.StringFlag{
+				Name:  "mask-b",
+				Usage: "Coverage image of the blue channel with --packed",
+			},
+			&cli.BoolFlag{
+				Name:  "invert",
+				Value: false,
+				Usage: "Invert the coverage of the alpha channel or --mask, dilating the transparent pixels into the opaque ones",
+			},
+			&cli.StringFlag{
+				Name:  "no-alpha",
+				Value: "passthrough",
+				Usage: "Behavior for inputs without transparency: passthrough, require (--mask or --key-color to choose the pixels to pad) or error",
+				Validator: func(mode string) error {
+					if mode != "passthrough" && mode != "require" && mode != "error" {
+						return fmt.Errorf("invalid --no-alpha mode %q, expected passthrough, require or error", mode)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "bias",
+				Value: 0,
+				Usage: "Treat color channels as signed data stored with this bias (e.g. 0.5 for vector displacement)",
+				Validator: func(bias float64) error {
+					if bias < 0 || bias >= 1 {
+						return fmt.Errorf("--bias must be in the range [0, 1), got %v", bias)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "color-space",
+				Value: "srgb",
+				Usage: "Space colors are blended in: srgb, or linear to decode sRGB to linear light first so averages don't darken",
+				Validator: func(name string) error {
+					_, err := uvpad.ParseColorSpace(name)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "normal-map",
+				Value: false,
+				Usage: "Treat the image as a normal map, renormalizing the dilated vectors",
+			},
+			&cli.StringFlag{
+				Name:  "normal-z",
+				Value: "keep",
+				Usage: "Z of dilated normals with --normal-map: keep, positive (flip inward normals) or reconstruct (from X and Y, for two-channel maps)",
+				Validator: func(name string) error {
+					_, err := uvpad.ParseNormalZ(name)
+					return err
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "open",
+				Value: 0,
+				Usage: "Remove stray opaque texels by opening the coverage by this many pixels before dilating",
+				Validator: func(open float64) error {
+					if open < 0 {
+						return fmt.Errorf("--open must not be negative, got %v", open)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "close",
+				Value: 0,
+				Usage: "Fill pinholes in the coverage by closing it by this many pixels before dilating",
+				Validator: func(close float64) error {
+					if close < 0 {
+						return fmt.Errorf("--close must not be negative, got %v", close)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "erode",
+				Value: 0,
+				Usage: "Shrink the opaque areas by this many pixels before dilating, replacing contaminated edges",
+				Validator: func(erode float64) error {
+					if erode < 0 {
+						return fmt.Errorf("--erode must not be negative, got %v", erode)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "radius",
+				Value: 0,
+				Usage: "Only dilate this many pixels from the opaque areas, leaving the rest transparent, 0 for no limit",
+				Validator: func(radius float64) error {
+					if radius < 0 {
+						return fmt.Errorf("--radius must not be negative, got %v", radius)
+					}
+					return nil
+				},
+			},
+			&cli.FloatFlag{
+				Name:  "radius-uv",
+				Value: 0,
+				Usage: "--radius as a fraction of UV space instead of pixels, scaled by the larger side of each image so one setting fits every resolution",
+				Validator: func(radius float64) error {
+					if radius < 0 || radius > 1 {
+						return fmt.Errorf("--radius-uv must be from 0 to 1, got %v", radius)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:  "mips",
+				Value: 0,
+				Usage: "Set --radius to the 2^N texels that padding must reach to survive N mip levels, 0 to keep --radius",
+				Validator: func(mips int64) error {
+					if mips < 0 || mips > 16 {
+						return fmt.Errorf("--mips must be from 0 to 16, got %d", mips)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:  "tile",
+				Value: 0,
+				Usage: "Dilate images larger than this many pixels on a side in tiles with a margin of --radius, so huge textures need memory for a tile only, 0 for the whole image",
+				Validator: func(tile int64) error {
+					if tile < 0 {
+						return fmt.Errorf("--tile must not be negative, got %d", tile)
+					}
+					return nil
+				},
+			},
+			&cli.IntFlag{
+				Name:  "alpha-threshold",
+				Value: 255,
+				Usage: "Minimum alpha (1-255) of the pixels whose color is dilated, lower ones are filled",
+				Validator: func(threshold int64) error {
+					if threshold < 1 || threshold > 255 {
+						return fmt.Errorf("--alpha-threshold must be in the range [1, 255], got %d", threshold)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "edge",
+				Value: "skip",
+				Usage: "Neighbours beyond the image border: skip, wrap (tiling) or mirror",
+				Validator: func(edge string) error {
+					_, err := uvpad.ParseEdge(edge)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "wrap",
+				Value: false,
+				Usage: "Treat the image as tiling, dilating across its edges, same as --edge wrap",
+			},
+			&cli.BoolFlag{
+				Name:  "composite",
+				Value: false,
+				Usage: "Blend partially transparent pixels over the dilated color instead of replacing them",
+			},
+			&cli.StringFlag{
+				Name:  "channels",
+				Value: "rgba",
+				Usage: "Channels that are dilated, such as rgb or r,g,b, the others are copied unchanged",
+				Validator: func(s string) error {
+					_, err := uvpad.ParseChannels(s)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "premultiplied",
+				Value: false,
+				Usage: "The colors of the image are premultiplied by alpha: unpremultiply before dilation and premultiply the result",
+			},
+			&cli.BoolFlag{
+				Name:  "keep-alpha",
+				Value: false,
+				Usage: "Only dilate the color, keeping the alpha channel of the input",
+			},
+			&cli.FloatFlag{
+				Name:  "fade-distance",
+				Value: 0,
+				Usage: "Fade the dilated colors toward --fade-color over this many pixels from the opaque areas, 0 to disable",
+				Validator: func(distance float64) error {
+					if distance < 0 {
+						return fmt.Errorf("--fade-distance must not be negative, got %v", distance)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "fade",
+				Value: "gaussian",
+				Usage: "Falloff of --fade-distance: gaussian or linear",
+				Validator: func(name string) error {
+					_, err := uvpad.ParseFalloff(name)
+					return err
+				},
+			},
+			&cli.StringFlag{
+				Name:  "fade-color",
+				Value: "#00000000",
+				Usage: "Color the dilation fades toward, #RRGGBB or #RRGGBBAA",
+				Validator: func(color string) error {
+					_, err := uvpad.ParseColor(color)
+					return err
+				},
+			},
+			&cli.IntFlag{
+				Name:  "jobs",
+				Value: 0,
+				Usage: "Number of files processed concurrently, 0 for --threads",
+			},
+			&cli.IntFlag{
+				Name:  "threads",
+				Value: 0,
+				Usage: "Number of CPUs used at once by the whole run, shared by the concurrent files, 0 for GOMAXPROCS or all of them",
+				Validator: func(threads int64) error {
+					if threads < 0 {
+						return fmt.Errorf("--threads must not be negative, got %d", threads)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:  "max-memory",
+				Value: "",
+				Usage: "Keep the estimated peak memory below this size (e.g. 2GiB) by dilating in tiles with a radius, then with 8-bit samples, and abort if it still doesn't fit",
+				Validator: func(size string) error {
+					_, err := parseSize(size)
+					return err
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "incremental",
+				Value: false,
+				Usage: "Skip inputs whose output is up to date with the input and settings",
+			},
+			&cli.StringFlag{
+				Name:      "state-file",
+				Value:     ".uvpad-state.json",
+				Usage:     "File recording produced outputs for --incremental",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:      "checkpoint",
+				Value:     "",
+				Usage:     "Record completed files here so that rerunning an interrupted batch skips them",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:      "checksums",
+				Value:     "",
+				Usage:     "Write a SHA256SUMS manifest of the produced files",
+				TakesFile: true,
+			},
+			&cli.StringFlag{
+				Name:      "manifest",
+				Value:     "",
+				Usage:     "JSON job file listing inputs with per-file outputs and settings",
+				TakesFile: true,
+			},
+			&cli.BoolFlag{
+				Name:  "recursive",
+				Value: false,
+				Usage: "Process every supported image in directory arguments and their subdirectories",
+			},
+			&cli.StringFlag{
+				Name:  "log-format",
+				Value: "text",
+				Usage: "Log format: text, or json for one JSON object per event",
+				Validator: func(format string) error {
+					if format != "text" && format != "json" {
+						return fmt.Errorf("invalid --log-format %q, expected text or json", format)
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Value:   false,
+				Usage:   "Only print errors",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Value:   false,
+				Usage:   "Also print the progress of the algorithms",
 			},
 			&cli.BoolFlag{
-				Name:  "slower",
+				Name:  "debug",
 				Value: false,
-				Usage: "If false, use the paint.net algorithm instead of GIMP UVPad algorithm",
+				Usage: "Also print diagnostics such as the settings of each file",
+			},
+			&cli.StringFlag{
+				Name:  "cpuprofile",
+				Usage: "Write a CPU profile of the processing to this file, for go tool pprof",
+			},
+			&cli.StringFlag{
+				Name:  "memprofile",
+				Usage: "Write a heap profile to this file after processing, for go tool pprof",
+			},
+			&cli.StringFlag{
+				Name:  "trace",
+				Usage: "Write an execution trace of the processing to this file, for go tool trace",
 			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
-			if cmd.NArg() != 1 {
-				fmt.Println("Usage: uvpad <input image>")
-				return nil
+			if err := loadConfig(cmd); err != nil {
+				return err
+			}
+			limitThreads(cmd)
+			if err := setVerbosity(cmd); err != nil {
+				return err
+			}
+			if name := findConfig(cmd.String("config")); name != "" {
+				logEvent(levelDebug, "config", name, nil, "Using configuration file %s\n", name)
 			}
-			input := cmd.Args().Get(0)
 
-			ext := path.Ext(input)
-			output := strings.TrimSuffix(input, ext) + "_padded" + ext
-			if cmd.String("output") != "" {
-				output = cmd.String("output")
+			if cmd.NArg() == 0 && cmd.String("manifest") == "" {
+				return fmt.Errorf("missing input, usage: uvpad <input image>...")
 			}
 
-			start := time.Now()
+			args := cmd.Args().Slice()
+			output := cmd.String("output")
+
+			// `uvpad <input> -` writes to stdout, for pipelines.
+			if len(args) == 2 && args[1] == "-" && output == "" {
+				args, output = args[:1], "-"
+			}
 
-			err := run(input, output, cmd.Bool("slower"))
+			// Outputs are only named with --suffix next to their inputs.
+			suffix := cmd.String("suffix")
+			if cmd.Bool("in-place") || cmd.String("output-dir") != "" {
+				suffix = ""
+			}
+			inputs, err := expandInputs(args, cmd.Bool("recursive"), suffix)
 			if err != nil {
 				return err
 			}
 
-			executionTime := time.Since(start)
-			fmt.Printf("Execution time: %v\n", executionTime)
+			var entries []manifestEntry
+			if cmd.String("manifest") != "" {
+				entries, err = loadManifest(cmd, cmd.String("manifest"))
+				if err != nil {
+					return err
+				}
+			}
+			if len(inputs)+len(entries) == 0 {
+				return fmt.Errorf("no input files")
+			}
 
-			fmt.Println("Saved padded image to", output)
+			if output != "" && len(inputs)+len(entries) > 1 {
+				return fmt.Errorf("--output can only be used with a single input")
+			}
+			for _, name := range fieldFlags {
+				if cmd.String(name) != "" && len(inputs)+len(entries) > 1 {
+					return fmt.Errorf("--%s can only be used with a single input", name)
+				}
+			}
+			if output != "" && cmd.String("output-dir") != "" {
+				return fmt.Errorf("--output and --output-dir can't be used together")
+			}
+			if cmd.Bool("in-place") && (output != "" || cmd.String("output-dir") != "") {
+				return fmt.Errorf("--in-place can't be used with --output or --output-dir")
+			}
 
-			return nil
-		},
-	}).Run(context.Background(), os.Args)
-}
+			for _, in := range inputs {
+				entries = append(entries, manifestEntry{input: in.path, output: output})
+				if dir := cmd.String("output-dir"); dir != "" {
+					entries[len(entries)-1].output = filepath.Join(dir, in.rel)
+				}
+			}
 
-func run(input, output string, slower bool) error {
-	inputFile, err := os.Open(input)
-	if err != nil {
-		return fmt.Errorf("failed to open input file: %w", err)
-	}
-	defer inputFile.Close()
+			var jobs []job
+			for _, entry := range entries {
+				j := job{input: entry.input, output: entry.output}
+				if j.output == "" {
+					j.output = defaultOutput(j.input, cmd.String("suffix"))
+					if cmd.Bool("in-place") {
+						j.output = j.input
+					}
+				}
+				if j.input == "-" && cmd.Bool("in-place") {
+					return fmt.Errorf("--in-place can't be used with stdin")
+				}
+				if j.output == "-" {
+					if len(entries) > 1 {
+						return fmt.Errorf("only a single input can be written to stdout")
+					}
+					console = os.Stderr
+				}
 
-	inputImage, err := png.Decode(inputFile)
-	if err != nil {
-		return fmt.Errorf("failed to decode input image: %w", err)
-	}
+				j.settings = newSettings(overrides{cmd, entry.overrides})
+				j.fingerprint = fingerprint(cmd, entry.overrides)
+				jobs = append(jobs, j)
+			}
 
-	var data image.Image
-	if slower {
-		data = process_gimp_alg(inputImage)
-	} else {
-		data = process_paint_net_alg(inputImage)
-	}
+			workers := int(cmd.Int("jobs"))
+			if workers <= 0 {
+				workers = runtime.GOMAXPROCS(0)
+			}
 
-	err = save(output, data)
-	if err != nil {
-		return fmt.Errorf("failed to save output image: %w", err)
-	}
-	return nil
-}
+			budget, _ := parseSize(cmd.String("max-memory"))
+			if budget == 0 {
+				budget = availableMemory()
+			}
+			memory := newMemoryBudget(budget)
 
-type Point struct {
-	x, y int
-}
+			var st *state
+			if cmd.Bool("incremental") {
+				st, err = loadState(cmd.String("state-file"))
+				if err != nil {
+					return withExitCode(exitRead, err)
+				}
+			}
 
-func process_paint_net_alg(input image.Image) image.Image {
-	bounds := input.Bounds()
-	width, height := bounds.Dx(), bounds.Dy()
+			if !jsonLog && verbosity >= levelNormal {
+				startProgress(len(jobs))
+			}
 
-	output := image.NewRGBA(bounds)
+			var ck *checkpoint
+			if name := cmd.String("checkpoint"); name != "" {
+				ck, err = openCheckpoint(name)
+				if err != nil {
+					return withExitCode(exitRead, err)
+				}
+			}
 
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			output.Set(x, y, input.At(x, y))
-		}
-	}
+			for i := range jobs {
+				jobs[i].budget = memory
+				jobs[i].state = st
+				jobs[i].checkpoint = ck
+				if verbosity >= levelVerbose && (workers == 1 || len(jobs) == 1) {
+					// Interleaved progress of several images is just noise.
+					jobs[i].opts.Log = consoleWriter{}
+				}
+				if jsonLog {
+					jobs[i].opts.Log = nil
+					jobs[i].opts.Progress = progressLogger(jobs[i].input)
+				} else if bar != nil {
+					jobs[i].opts.Progress = progressUpdater(jobs[i].input)
+				}
+			}
 
-	opaqueMask := make([]bool, width*height)
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			_, _, _, alpha := input.At(x, y).RGBA()
-			if alpha == 0xffff {
-				r, g, b, _ := input.At(x, y).RGBA()
-				output.Set(x, y, color.RGBA{
-					uint8(r),
-					uint8(g),
-					uint8(b),
-					255,
-				})
-				opaqueMask[y*width+x] = true
+			prof, err := startProfiles(cmd)
+			if err != nil {
+				return withExitCode(exitWrite, err)
+			}
+			results := runBatch(jobs, workers)
+			if bar != nil {
+				stopProgress()
+			}
+			if err := prof.stop(); err != nil {
+				return withExitCode(exitWrite, err)
 			}
-		}
-	}
 
-	nearest := jumpFlood(width, height, opaqueMask)
+			if ck != nil {
+				if err := ck.close(failures(results) == nil); err != nil {
+					return withExitCode(exitWrite, err)
+				}
+			}
 
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			idx := y*width + x
-			_, _, _, alpha := input.At(x, y).RGBA()
+			if st != nil {
+				if err := st.save(); err != nil {
+					return withExitCode(exitWrite, fmt.Errorf("failed to write state file: %w", err))
+				}
+			}
 
-			if alpha != 0xffff {
-				point := nearest[idx]
-				if point.x != -1 && point.y != -1 {
-					r, g, b, _ := input.At(point.x, point.y).RGBA()
-					output.Set(x, y, color.RGBA{
-						uint8(r >> 8),
-						uint8(g >> 8),
-						uint8(b >> 8),
-						255,
-					})
-				} else {
-					output.Set(x, y, color.RGBA{0, 0, 0, 0})
+			if (len(results) > 1 || jsonLog) && verbosity >= levelNormal {
+				printSummary(results)
+			}
+
+			if manifest := cmd.String("checksums"); manifest != "" {
+				var checksums []checksum
+				for _, r := range results {
+					if r.err == nil {
+						checksums = append(checksums, checksum{r.output, r.digest})
+					}
+				}
+				err := writeChecksums(manifest, checksums)
+				if err != nil {
+					return withExitCode(exitWrite, err)
 				}
 			}
-		}
-	}
 
-	return output
+			return failures(results)
+		},
+	}).Run(context.Background(), os.Args)
+	if err != nil {
+		logError("", err)
+		os.Exit(exitCode(err))
+	}
 }
 
-func jumpFlood(width, height int, opaqueMask []bool) []Point {
-	distances := make([]float64, width*height)
-	nearest := make([]Point, width*height)
+// job is a single input image, the path its padded result is saved to and
+// the settings it is processed with.
+type job struct {
+	input, output string
+	settings
+}
 
-	maxDistance := float64(width*width + height*height)
+// limitThreads caps the CPUs the run uses at once at --threads. The
+// algorithms and the default number of jobs follow GOMAXPROCS, so the limit
+// holds across concurrent files.
+func limitThreads(cmd flagReader) {
+	if threads := cmd.Int("threads"); threads > 0 {
+		runtime.GOMAXPROCS(int(threads))
+	}
+}
 
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			idx := y*width + x
-			if opaqueMask[idx] {
-				distances[idx] = 0
-				nearest[idx] = Point{x, y}
-			} else {
-				distances[idx] = maxDistance
-				nearest[idx] = Point{-1, -1}
-			}
-		}
+// defaultOutput is the output path used when none is given: the input path
+// with the suffix inserted before the extension, or stdout for stdin.
+func defaultOutput(input, suffix string) string {
+	if input == "-" {
+		return "-"
+	}
+	ext := archiveExt(input)
+	if ext == "" {
+		ext = path.Ext(input)
 	}
+	return strings.TrimSuffix(input, ext) + suffix + ext
+}
 
-	numCpu := runtime.NumCPU()
-	maxSteps := int(math.Ceil(math.Log2(float64(math.Max(float64(width), float64(height)))))) * 2
-	for step := 1; step < maxSteps; step++ {
-		var wg sync.WaitGroup
-		chunkSize := height / numCpu
-		if chunkSize == 0 {
-			chunkSize = 1
-		}
+func run(j job) (string, error) {
+	input, output := j.input, j.output
+	s := j.settings
 
-		distancesCopy := make([]float64, len(distances))
-		copy(distancesCopy, distances)
+	if !s.inPlace && sameFile(input, output) {
+		return "", fmt.Errorf("output %s is the input file, use --in-place to overwrite it", output)
+	}
+	if archiveExt(input) != "" {
+		return runArchive(j)
+	}
+	if isRaw(input) {
+		return runRaw(j)
+	}
 
-		nearestCopy := make([]Point, len(nearest))
-		copy(nearestCopy, nearest)
+	inputFile, err := openInput(input)
+	if err != nil {
+		return "", withExitCode(exitRead, fmt.Errorf("failed to open input file: %w", err))
+	}
+	defer inputFile.Close()
 
-		for i := 0; i < numCpu; i++ {
-			wg.Add(1)
-			start := i * chunkSize
-			end := (i + 1) * chunkSize
-			if i == numCpu-1 {
-				end = height
+	var digest string
+	err = pad(j, input, inputFile, func(data image.Image, _ string) error {
+		if s.backup {
+			if err := backup(input); err != nil {
+				return withExitCode(exitWrite, err)
 			}
-
-			go func(start, end int) {
-				defer wg.Done()
-				processJumpFlood(width, height, distancesCopy, nearestCopy, distances, nearest, step, start, end)
-			}(start, end)
 		}
 
-		wg.Wait()
-	}
-
-	return nearest
+		var err error
+		digest, err = save(output, data, s.format)
+		if err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save output image: %w", err))
+		}
+		return nil
+	})
+	return digest, err
 }
 
-func processJumpFlood(width, height int, distancesCopy []float64, nearestCopy []Point, distances []float64, nearest []Point, step, start, end int) {
-	neighbours := []struct{ dx, dy int }{
-		{-step, -step}, {0, -step}, {step, -step},
-		{-step, 0}, {step, 0},
-		{-step, step}, {0, step}, {step, step},
-	}
-
-	for y := start; y < end; y++ {
-		for x := 0; x < width; x++ {
-			idx := y*width + x
-			bestDistance := distancesCopy[idx]
-
-			for _, neighbour := range neighbours {
-				nx, ny := x+neighbour.dx, y+neighbour.dy
-				if nx >= 0 && nx < width && ny >= 0 && ny < height {
-					neighbourIdx := ny*width + nx
-
-					if nearestCopy[neighbourIdx].x != -1 && nearestCopy[neighbourIdx].y != -1 {
-						npx, npy := nearestCopy[neighbourIdx].x, nearestCopy[neighbourIdx].y
-						dx := float64(x - npx)
-						dy := float64(y - npy)
-						distance := dx*dx + dy*dy
-
-						if distance < bestDistance {
-							distances[idx] = distance
-							nearest[idx] = nearestCopy[neighbourIdx]
-							bestDistance = distance
-						}
-					}
-				}
-			}
-		}
+// pad decodes the image named name from r, dilates it with the settings of
+// the job and passes the result and the decoded format to write, within the
+// memory budget of the job.
+func pad(j job, name string, r io.ReadSeeker, write func(data image.Image, format string) error) error {
+	config, _, err := uvpad.DecodeConfig(r)
+	if err != nil {
+		return withExitCode(exitRead, fmt.Errorf("failed to decode input image: %w", err))
 	}
+	return padImage(j, name, config, func() (image.Image, string, error) {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, "", withExitCode(exitRead, fmt.Errorf("failed to rewind input file: %w", err))
+		}
+		img, format, err := uvpad.Decode(r)
+		if err != nil {
+			return nil, "", withExitCode(exitRead, fmt.Errorf("failed to decode input image: %w", err))
+		}
+		return img, format, nil
+	}, write)
 }
 
-func process_gimp_alg(input image.Image) image.Image {
-	bounds := input.Bounds()
-	width, height := bounds.Dx(), bounds.Dy()
-	rgba := image.NewRGBA(bounds)
+// padImage is pad for an image with the given header, which decode returns
+// once the memory it needs is available.
+func padImage(j job, name string, config image.Config, decode func() (image.Image, string, error), write func(data image.Image, format string) error) error {
+	s, opts, maxMemory := j.settings, j.opts, j.maxMemory
 
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			rgba.Set(x, y, input.At(x, y))
-		}
+	alg, err := uvpad.Lookup(s.algorithm)
+	if err != nil {
+		return err
+	}
+	if len(s.ops) > 0 {
+		alg = uvpad.Pipeline(alg, s.ops)
 	}
 
-	output := image.NewRGBA(bounds)
-	copy(output.Pix, rgba.Pix)
-
-	remaining := 0
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			_, _, _, alpha := rgba.At(x, y).RGBA()
-			if alpha != 0xffff {
-				remaining++
+	if s.mask != "" {
+		opts.Mask, err = loadMask(s.mask)
+		if err != nil {
+			return err
+		}
+	}
+	if s.radiusUV > 0 {
+		opts.Radius = s.radiusUV * float64(max(config.Width, config.Height))
+	}
+	if opts.Tile > 0 && opts.Radius == 0 {
+		return fmt.Errorf("--tile needs --radius, --radius-uv or --mips to size the margin of the tiles")
+	}
+	var mesh *uvpad.Mesh
+	if s.mesh != "" {
+		mesh, err = loadMesh(s.mesh, s.meshFilter)
+		if err != nil {
+			return err
+		}
+		opts.Mask = mesh.Rasterize(config.Width, config.Height)
+		if s.meshReport {
+			reportMesh(name, mesh.Report(config.Width, config.Height), opts.Radius)
+		}
+		if s.perIsland {
+			opts.Islands = mesh.Islands(config.Width, config.Height)
+		}
+	} else if s.perIsland {
+		return fmt.Errorf("--per-island needs the islands of --mesh")
+	} else if s.debugWireframe {
+		return fmt.Errorf("--debug-wireframe needs the UVs of --mesh")
+	} else if s.stitchSeams {
+		return fmt.Errorf("--stitch-seams needs the topology of --mesh")
+	}
+	if s.islands != "" {
+		islands, err := loadMask(s.islands)
+		if err != nil {
+			return err
+		}
+		opts.Islands = uvpad.IslandsFromImage(islands)
+	}
+	if s.atlas != "" {
+		rects, err := loadAtlas(s.atlas)
+		if err != nil {
+			return err
+		}
+		opts.Islands = uvpad.AtlasIslands(config.Width, config.Height, rects)
+	}
+	if s.grid != "" {
+		columns, rows, _ := uvpad.ParseGrid(s.grid)
+		opts.Islands = uvpad.AtlasIslands(config.Width, config.Height, uvpad.GridRects(config.Width, config.Height, columns, rows, s.gridPadding))
+	}
+	if opts.Islands != nil {
+		opts.Islands.Gutter = s.gutter
+	}
+	if opts.Packed {
+		for c, path := range s.packedMasks {
+			if path == "" {
+				continue
+			}
+			opts.ChannelMasks[c], err = loadMask(path)
+			if err != nil {
+				return err
 			}
 		}
 	}
+	logEvent(levelDebug, "settings", name, fields{
+		"algorithm": s.algorithm,
+		"bias":      opts.Bias,
+		"format":    s.format,
+		"width":     config.Width,
+		"height":    config.Height,
+	}, "Processing %s (%dx%d) with %s, bias %v, format %q\n", name, config.Width, config.Height, s.algorithm, opts.Bias, s.format)
+	fit := fitMemory(config, alg, opts, maxMemory)
+	opts, memory := fit.opts, fit.memory
+	logEvent(levelNormal, "memory", name, fields{"bytes": memory}, "Estimated peak memory for %s: %s\n", name, formatSize(memory))
+	if maxMemory > 0 && memory > maxMemory {
+		return withExitCode(exitVerify, fmt.Errorf("estimated peak memory %s %w of %s", formatSize(memory), errOverBudget, formatSize(maxMemory)))
+	}
+	if opts.Tile != j.opts.Tile {
+		logEvent(levelNormal, "strategy", name, fields{"tile": opts.Tile}, "Dilating %s in %d pixel tiles to fit --max-memory\n", name, opts.Tile)
+	}
+	if fit.reduce {
+		logEvent(levelNormal, "strategy", name, fields{"depth": 8}, "Warning: dilating %s with 8-bit samples to fit --max-memory\n", name)
+	}
+	s.budget.acquire(memory)
+	defer s.budget.release(memory)
 
-	passes := 0
-	for remaining > 0 {
-		fmt.Printf("Pass %d: %d remaining\n", passes, remaining)
-		passes++
-
-		tempImg := image.NewRGBA(bounds)
-		copy(tempImg.Pix, output.Pix)
-
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				pixelIdx := y*output.Stride + x*4
-				alpha := output.Pix[pixelIdx+3]
-
-				if alpha != 255 {
-					var r, g, b uint32
-					var count uint32
-
-					neighbours := []struct{ dx, dy int }{
-						{-1, 0}, {1, 0}, {0, -1}, {0, 1},
-					}
+	inputImage, format, err := decode()
+	if err != nil {
+		return err
+	}
 
-					for _, n := range neighbours {
-						nx, ny := x+n.dx, y+n.dy
-						if nx >= 0 && nx < width && ny >= 0 && ny < height {
-							nr, ng, nb, na := rgba.At(nx, ny).RGBA()
-							if na == 0xffff {
-								r += nr
-								g += ng
-								b += nb
-								count++
-							}
-						}
-					}
+	logEvent(levelDebug, "decoded", name, fields{"model": fmt.Sprintf("%T", inputImage)}, "Decoded %s as %T\n", name, inputImage)
 
-					if count > 0 {
-						tempImg.Pix[pixelIdx] = uint8((r / count) >> 8)
-						tempImg.Pix[pixelIdx+1] = uint8((g / count) >> 8)
-						tempImg.Pix[pixelIdx+2] = uint8((b / count) >> 8)
-						tempImg.Pix[pixelIdx+3] = 255 // Make fully opaque
-						remaining--
-					}
-				}
-			}
-			if y%20 == 0 {
-				progress := float64(height*width-remaining) / float64(height*width)
-				fmt.Printf("Progress: %.1f%%\r", progress*100)
+	var data image.Image
+	switch {
+	case s.sdf:
+		data = signedDistanceField(s, alg, inputImage)
+	case opts.Seed == uvpad.SeedAlpha && opts.Mask == nil && opts.ChannelMasks == [3]image.Image{} && opts.KeyColor == nil && isOpaque(inputImage):
+		switch s.noAlpha {
+		case "require":
+			return withExitCode(exitUsage, fmt.Errorf("input image %s has no transparent pixels, pass --mask or --key-color to choose the pixels to pad", name))
+		case "error":
+			return withExitCode(exitVerify, fmt.Errorf("input image %s has no transparent pixels to pad", name))
+		}
+		logEvent(levelNormal, "passthrough", name, nil, "Input image has no transparent pixels, passing it through unchanged\n")
+		data = inputImage
+	default:
+		src := uvpad.FromImage(inputImage)
+		// The buffers replaced along the way are reused by the next input of
+		// a batch.
+		if b, ok := src.(*uvpad.Buffer[uint16]); ok && fit.reduce {
+			src = uvpad.Convert[uint8](b)
+			uvpad.Release(b)
+		}
+		if s.stitchSeams {
+			stitched := uvpad.StitchSeams(src, mesh, opts)
+			uvpad.Release(src)
+			src = stitched
+		}
+		dst := alg.Process(src, opts)
+		if f, ok := dst.(*uvpad.Buffer[float32]); ok {
+			// Float samples, only read from raw buffers, stay unclamped.
+			data = uvpad.FloatImage{Buffer: f}
+		} else {
+			// 16-bit results are copied into data, so their buffers are
+			// released; 8-bit ones share their pixels with it.
+			data = dst.Image()
+			if dst != src {
+				uvpad.Release(dst)
 			}
 		}
+		uvpad.Release(src)
+	}
+	if s.islandMap != "" {
+		islands := opts.Islands
+		if islands == nil && mesh != nil {
+			islands = mesh.Islands(config.Width, config.Height)
+		}
+		if islands == nil {
+			return fmt.Errorf("--island-map needs the islands of --mesh, --islands, --atlas or --grid")
+		}
+		if _, err := save(s.islandMap, islands.Image(config.Width, config.Height, opts), ""); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save island map: %w", err))
+		}
+	}
+	if s.debugWireframe {
+		if err := writeWireframe(j, mesh, data); err != nil {
+			return err
+		}
+	}
+	if s.fields.requested() {
+		if err := writeFieldOutputs(s.fields, alg, uvpad.FromImage(inputImage), opts); err != nil {
+			return err
+		}
+	}
+
+	return write(data, format)
+}
 
-		copy(output.Pix, tempImg.Pix)
-		copy(rgba.Pix, output.Pix)
+// loadAtlas reads the rectangles of an atlas.
+func loadAtlas(path string) ([]image.Rectangle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to open atlas: %w", err))
 	}
+	defer f.Close()
 
-	return output
+	rects, err := uvpad.ParseAtlas(f)
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to read atlas %s: %w", path, err))
+	}
+	return rects, nil
 }
 
-func save(output string, data image.Image) error {
-	outputFile, err := os.Create(output)
+// loadMask decodes a coverage mask image.
+func loadMask(path string) (image.Image, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to open mask image: %w", err))
 	}
-	defer outputFile.Close()
+	defer f.Close()
 
-	err = png.Encode(outputFile, data)
+	mask, _, err := uvpad.Decode(f)
 	if err != nil {
-		return fmt.Errorf("failed to encode output image: %w", err)
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to decode mask image %s: %w", path, err))
+	}
+	return mask, nil
+}
+
+// isOpaque reports whether the image has no transparent pixels, either because
+// its color model has no alpha channel (JPEG, grayscale) or because every
+// pixel is fully opaque.
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			_, _, _, alpha := img.At(x, y).RGBA()
+			if alpha != 0xffff {
+				return false
+			}
+		}
 	}
-	return nil
+	return true
 }
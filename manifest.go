@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/urfave/cli/v3"
+)
+
+// manifestEntry is one job of a --manifest file.
+type manifestEntry struct {
+	input, output string
+	overrides     map[string]any
+}
+
+// loadManifest reads a job file listing inputs with optional output paths and
+// per-file overrides of any processing flag, keyed by flag name:
+//
+//	{
+//	  "jobs": [
+//	    {"input": "albedo.png"},
+//	    {"input": "normal.png", "output": "out/normal.png", "algorithm": "gimp"}
+//	  ]
+//	}
+//
+// Relative paths, of the inputs and outputs as well as of the files of
+// settings such as "mask" and "mesh", are resolved from the manifest's
+// directory.
+func loadManifest(cmd *cli.Command, name string) ([]manifestEntry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest struct {
+		Jobs []map[string]any `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", name, err)
+	}
+
+	dir := filepath.Dir(name)
+	resolve := func(p string) string {
+		if p == "-" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	var entries []manifestEntry
+	for i, fields := range manifest.Jobs {
+		input, ok := fields["input"].(string)
+		if !ok || input == "" {
+			return nil, fmt.Errorf("%s: job %d has no input", name, i)
+		}
+		entry := manifestEntry{input: resolve(input), overrides: map[string]any{}}
+
+		for key, value := range fields {
+			switch key {
+			case "input":
+			case "output":
+				output, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: job %d: output must be a string", name, i)
+				}
+				entry.output = resolve(output)
+			default:
+				v, err := validateOverride(cmd, key, value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: job %d: %w", name, i, err)
+				}
+				if path, ok := v.(string); ok && path != "" && slices.Contains(fileFlags, key) {
+					v = resolve(path)
+				}
+				entry.overrides[key] = v
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
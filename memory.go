@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+var sizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseSize parses a byte count such as "512MiB", "4G" or "1000000".
+func parseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			scale = unit.scale
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(upper, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * float64(scale)), nil
+}
+
+// formatSize formats a byte count with a binary unit.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// estimatePeakMemory estimates the peak memory of processing an image with
+// the given header, counting the decoded source and the encoded result on
+// top of what the algorithm itself needs, unless they share their pixels
+// with its buffers.
+func estimatePeakMemory(config image.Config, alg uvpad.Algorithm, opts uvpad.Options) int64 {
+	sampleSize := sampleSize(config)
+	imageBytes := int64(config.Width) * int64(config.Height) * 4 * int64(sampleSize)
+	copies := int64(2)
+	if config.ColorModel == color.NRGBAModel || config.ColorModel == uvpad.FloatModel {
+		// Decoded as an *image.NRGBA or a FloatImage, which the algorithm
+		// reads in place.
+		copies--
+	}
+	if sampleSize != 2 {
+		// The result is an *image.NRGBA on the output buffer, or a
+		// FloatImage on it.
+		copies--
+	}
+	return copies*imageBytes + uvpad.EstimateMemory(alg, config.Width, config.Height, sampleSize, opts)
+}
+
+// minTile is the smallest tile tried to fit --max-memory: smaller ones are
+// mostly margin.
+const minTile = 64
+
+// strategy is how an image is processed to fit --max-memory.
+type strategy struct {
+	opts uvpad.Options
+	// reduce dilates 16-bit images with 8-bit samples.
+	reduce bool
+	memory int64
+}
+
+// fitMemory returns the cheapest way of processing an image that keeps its
+// estimated peak memory within maxMemory: at once, in tiles of halving sizes
+// when there is a radius to size their margin, then with 8-bit samples if
+// the image has 16. The last strategy tried exceeds maxMemory if none fits.
+func fitMemory(config image.Config, alg uvpad.Algorithm, opts uvpad.Options, maxMemory int64) strategy {
+	s := strategy{opts: opts, memory: estimatePeakMemory(config, alg, opts)}
+	if maxMemory <= 0 || s.memory <= maxMemory {
+		return s
+	}
+
+	estimate := func(opts uvpad.Options, reduce bool) strategy {
+		if !reduce {
+			return strategy{opts: opts, memory: estimatePeakMemory(config, alg, opts)}
+		}
+		// The decoded 16-bit source is held next to its 8-bit copy.
+		reduced := config
+		reduced.ColorModel = color.NRGBAModel
+		source := int64(config.Width) * int64(config.Height) * 4 * 2
+		return strategy{opts: opts, reduce: true, memory: source + estimatePeakMemory(reduced, alg, opts)}
+	}
+	for _, reduce := range []bool{false, true} {
+		if reduce {
+			if sampleSize(config) != 2 {
+				// Only 16-bit samples are reduced.
+				break
+			}
+			if s = estimate(opts, true); s.memory <= maxMemory {
+				return s
+			}
+		}
+		if opts.Radius <= 0 {
+			continue
+		}
+
+		tile := opts.Tile
+		if tile <= 0 {
+			tile = max(config.Width, config.Height) / 2
+		}
+		for ; tile >= minTile; tile /= 2 {
+			tiled := opts
+			tiled.Tile = tile
+			if s = estimate(tiled, reduce); s.memory <= maxMemory {
+				return s
+			}
+		}
+	}
+	return s
+}
+
+// sampleSize returns the bytes per channel of the images of config.
+func sampleSize(config image.Config) int {
+	switch config.ColorModel {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return 2
+	case uvpad.FloatModel:
+		return 4
+	}
+	return 1
+}
+
+// availableMemory returns the memory available to new allocations as reported
+// by the system, or 0 where unknown.
+func availableMemory() int64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// errOverBudget marks images whose estimated peak memory exceeds the memory
+// budget however they're dilated.
+var errOverBudget = errors.New("exceeds the memory budget")
+
+// memoryBudget bounds the total estimated memory of the images processed
+// concurrently. A nil budget is unlimited.
+type memoryBudget struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	available int64
+	capacity  int64
+}
+
+func newMemoryBudget(capacity int64) *memoryBudget {
+	if capacity <= 0 {
+		return nil
+	}
+	b := &memoryBudget{available: capacity, capacity: capacity}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire blocks until n bytes are available. Requests larger than the whole
+// budget wait for it to be entirely free instead of blocking forever.
+func (b *memoryBudget) acquire(n int64) {
+	if b == nil {
+		return
+	}
+	n = min(n, b.capacity)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.available < n {
+		b.cond.Wait()
+	}
+	b.available -= n
+}
+
+func (b *memoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+	n = min(n, b.capacity)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.available += n
+	b.cond.Broadcast()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/urfave/cli/v3"
+)
+
+// loadMesh reads the UV layout of the parts of a model selected by filter,
+// OBJ, glTF or FBX by its extension.
+func loadMesh(path string, filter uvpad.MeshFilter) (*uvpad.Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to open mesh: %w", err))
+	}
+	defer f.Close()
+
+	var mesh *uvpad.Mesh
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gltf", ".glb":
+		// External buffers are relative to the model.
+		mesh, err = uvpad.ParseGLTF(f, func(uri string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(filepath.Dir(path), filepath.FromSlash(uri)))
+		}, filter)
+	case ".fbx":
+		mesh, err = uvpad.ParseFBX(f, filter)
+	default:
+		mesh, err = uvpad.ParseOBJ(f, filter)
+	}
+	if err != nil {
+		return nil, withExitCode(exitRead, fmt.Errorf("failed to read mesh %s: %w", path, err))
+	}
+	if len(mesh.Triangles) == 0 {
+		return nil, withExitCode(exitRead, fmt.Errorf("mesh %s has no UV triangles matching the selection", path))
+	}
+	return mesh, nil
+}
+
+// reportMesh logs the texel density of every UV island of a mesh, its
+// coverage and the smallest gap between islands, with a warning when the
+// gap is narrower than the radius of the padding.
+func reportMesh(name string, r uvpad.MeshReport, radius float64) {
+	for i, island := range r.Islands {
+		logEvent(levelNormal, "island", name, fields{
+			"island":    i,
+			"triangles": island.Triangles,
+			"texels":    island.Texels,
+			"area":      island.Area,
+			"density":   island.Density,
+		}, "Island %d of %s: %d triangles, %.0f texels, %.2f texels per unit\n", i, name, island.Triangles, island.Texels, island.Density)
+	}
+
+	gap, gapText := any(nil), "none"
+	if !math.IsInf(r.MinGap, 1) {
+		gap, gapText = r.MinGap, fmt.Sprintf("%.1f pixels", r.MinGap)
+	}
+	logEvent(levelNormal, "coverage", name, fields{"coverage": r.Coverage, "islands": len(r.Islands), "gap": gap},
+		"UV coverage of %s: %.1f%% in %d islands, smallest gap between islands %s\n", name, 100*r.Coverage, len(r.Islands), gapText)
+	if radius > 0 && r.MinGap < radius {
+		logEvent(levelNormal, "gap", name, fields{"gap": r.MinGap, "radius": radius},
+			"Warning: islands of %s are %.1f pixels apart, less than the radius %v, so the padding bleeds between them\n", name, r.MinGap, radius)
+	}
+}
+
+// maskCommand writes the coverage mask rasterized from the UVs of a mesh
+// without dilating anything, for other tools to reuse.
+var maskCommand = &cli.Command{
+	Name:      "mask",
+	Aliases:   []string{"rasterize"},
+	Usage:     "Write the coverage mask rasterized from the UV triangles of a mesh",
+	ArgsUsage: "<mesh>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "size",
+			Usage:    "Size of the mask in pixels, such as 2048x1024, or 2048 for a square",
+			Required: true,
+			Validator: func(s string) error {
+				_, _, err := parseDimensions(s)
+				return err
+			},
+		},
+		&cli.IntFlag{
+			Name:  "anti-alias",
+			Value: 1,
+			Usage: "Sample every texel on an N by N grid for an anti-aliased coverage instead of a hard mask",
+			Validator: func(samples int64) error {
+				if samples < 1 || samples > 16 {
+					return fmt.Errorf("--anti-alias must be from 1 to 16, got %d", samples)
+				}
+				return nil
+			},
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.NArg() != 1 {
+			return fmt.Errorf("expected a single mesh, usage: uvpad mask --size <size> <mesh>")
+		}
+		path := cmd.Args().First()
+		width, height, _ := parseDimensions(cmd.String("size"))
+
+		mesh, err := loadMesh(path, newSettings(cmd).meshFilter)
+		if err != nil {
+			return err
+		}
+		var mask image.Image
+		if samples := int(cmd.Int("anti-alias")); samples > 1 {
+			mask = mesh.Coverage(width, height, samples)
+		} else {
+			mask = mesh.Rasterize(width, height)
+		}
+
+		output := cmd.String("output")
+		if output == "" {
+			output = strings.TrimSuffix(path, filepath.Ext(path)) + "_mask.png"
+		}
+		if _, err := save(output, mask, formatName(cmd.String("format"))); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to save mask: %w", err))
+		}
+		if output != "-" && !cmd.Bool("quiet") {
+			printf("Saved mask to %s\n", output)
+		}
+		return nil
+	},
+}
+
+// parseDimensions parses an image size written as WxH, or a single number
+// for a square.
+func parseDimensions(s string) (width, height int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		h = w
+	}
+	width, err = strconv.Atoi(w)
+	if err == nil {
+		height, err = strconv.Atoi(h)
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, expected a width and height such as 2048x1024", s)
+	}
+	return width, height, nil
+}
+
+// writeWireframe saves the padded image of a job with the UV wireframe of
+// the mesh drawn over it next to its output, with a _debug suffix.
+func writeWireframe(j job, mesh *uvpad.Mesh, data image.Image) error {
+	if j.output == "-" || archiveExt(j.input) != "" {
+		return fmt.Errorf("--debug-wireframe needs an output file")
+	}
+	bounds := data.Bounds()
+	debug := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(debug, debug.Rect, data, bounds.Min, draw.Src)
+	mesh.DrawWireframe(debug, j.wireframeColor)
+
+	output := defaultOutput(j.output, "_debug")
+	if _, err := save(output, debug, ""); err != nil {
+		return withExitCode(exitWrite, fmt.Errorf("failed to save wireframe image: %w", err))
+	}
+	return nil
+}
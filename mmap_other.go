@@ -0,0 +1,24 @@
+//go:build !unix
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the first size bytes of f into memory where files can't be
+// memory-mapped. unmap writes a writable copy back to f.
+func mapFile(f *os.File, size int, writable bool) (data []byte, unmap func() error, err error) {
+	data = make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(f, 0, int64(size)), data); err != nil && !writable {
+		return nil, nil, err
+	}
+	return data, func() error {
+		if !writable {
+			return nil
+		}
+		_, err := f.WriteAt(data, 0)
+		return err
+	}, nil
+}
@@ -0,0 +1,279 @@
+// Package uvpad implements texture dilation: filling the transparent areas of
+// a texture with the colors of nearby opaque texels so that filtering and
+// mipmapping don't bleed the background into UV islands.
+package uvpad
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Options configures a single dilation run.
+type Options struct {
+	// Log receives progress messages from algorithms that report them.
+	// A nil Log discards them.
+	Log io.Writer
+
+	// Progress, if set, is called with the completed fraction of the work,
+	// from 0 to 1, on the goroutine that called Process.
+	Progress func(fraction float64)
+
+	// Bias marks the color channels as signed data stored biased, such as
+	// vector displacement where 0.5 encodes zero. The channels are decoded to
+	// signed space before dilation and re-biased on write, so averages and
+	// unreached pixels are computed around the encoded zero. Zero disables it.
+	Bias float64
+
+	// ColorSpace selects the space in which colors are blended by the
+	// algorithms that average them. ColorSpaceLinear is ignored for biased
+	// data, normal maps and packed channels, which aren't colors.
+	ColorSpace ColorSpace
+
+	// NormalMap treats the color channels as a normal map: the vectors are
+	// decoded with a bias of 0.5 (unless Bias is set), dilated, and every
+	// filled pixel is renormalized, with its Z treated as NormalZ selects.
+	NormalMap bool
+	NormalZ   NormalZ
+
+	// Seed is the source of the coverage that decides which pixels are
+	// seeds: the alpha channel, or with SeedLuminance the pixels whose
+	// luminance is above Threshold (0 to 1).
+	Seed      Seed
+	Threshold float64
+
+	// KeyColor, if set, seeds images without an alpha channel, such as
+	// sprite sheets on a magenta background: pixels whose color is within
+	// KeyTolerance (0 to 1, per channel) of it are empty, every other pixel
+	// is a seed.
+	KeyColor     *Color
+	KeyTolerance float64
+
+	// Mask, if set, replaces the alpha channel as the coverage that decides
+	// which pixels are seeds: its alpha if it has transparent pixels, its
+	// luminance otherwise, so fully opaque textures such as lightmaps can be
+	// dilated by their UV coverage. A mask of another size is scaled to the
+	// image.
+	Mask image.Image
+
+	// Islands, when set, dilates every UV island on its own, so that the
+	// colors of one island never reach the gutter of another. Pixels outside
+	// every island are filled as its Gutter says.
+	Islands *IslandMap
+
+	// Packed treats the color channels as independent data, such as the
+	// occlusion, roughness and metallic of an ORM map: they are never
+	// blended in linear light, and each of them with a mask in ChannelMasks
+	// (red, green, blue) is dilated separately, seeded by that mask, so the
+	// channels don't contaminate each other.
+	Packed       bool
+	ChannelMasks [3]image.Image
+
+	// Invert swaps the seeds and the region to fill: pixels are covered by
+	// the inverse of their alpha, or of the mask, for coverage conventions
+	// where white means empty.
+	Invert bool
+
+	// Open removes the seeds that don't survive shrinking and regrowing the
+	// opaque areas by this many pixels, such as isolated stray texels, and
+	// Close turns pinholes and gaps that growing and shrinking them fills
+	// into seeds with the color of their nearest seed. Both run before the
+	// dilation, open first.
+	Open, Close float64
+
+	// Erode shrinks the opaque areas by this many pixels before the
+	// dilation, so that their contaminated edges are replaced by the colors
+	// further in.
+	Erode float64
+
+	// Radius limits the dilation to pixels within this many pixels of an
+	// opaque pixel, the rest stays transparent. Zero dilates the whole image.
+	Radius float64
+
+	// Tile, with a Radius, dilates images larger than Tile pixels on a side
+	// in Tile by Tile tiles with a margin of the radius around each, so that
+	// the scratch memory of the algorithms scales with the tile rather than
+	// the image. Each pixel only takes colors from within the radius, so edt
+	// and gimp dilate tiles as they dilate the whole image and jfa within the
+	// error of the jump flood; the pyramids of pushpull and diffusion only
+	// see the margin. Zero disables tiling.
+	Tile int
+
+	// AlphaThreshold is the minimum alpha, from 0 to 1, of the pixels whose
+	// color is dilated. Pixels below it are filled. Zero uses only fully
+	// opaque pixels.
+	AlphaThreshold float64
+
+	// Edge selects how neighbours beyond the border of the image are
+	// treated.
+	Edge Edge
+
+	// Metric is the distance used to find the nearest opaque pixel by the
+	// jfa and edt algorithms.
+	Metric Metric
+
+	// Blend fills pixels with the average color of this many nearest opaque
+	// pixels, weighted by inverse distance, instead of the color of the
+	// nearest one, which softens the hard Voronoi edges between seeds. Used
+	// by the jfa and edt algorithms, zero or one copies the nearest color.
+	Blend int
+
+	// Iterations is the number of relaxation iterations per pyramid level of
+	// the diffusion algorithm. Zero uses DefaultIterations.
+	Iterations int
+
+	// Connectivity is the neighbourhood of the gimp algorithm: 4 (the
+	// default, also used for zero) for the pixels along the axes, or 8 to
+	// include the diagonals.
+	Connectivity int
+
+	// Kernel is the radius of the neighbourhood of the gimp algorithm, so
+	// every pass grows the filled area by that many pixels. Zero or one uses
+	// the adjacent pixels.
+	Kernel int
+
+	// JFA selects extra passes of the jump flood for better accuracy.
+	JFA JFAVariant
+
+	// FadeDistance fades the dilated colors toward FadeColor with the
+	// distance from the opaque pixels, along the Fade curve, over this many
+	// pixels. Zero disables fading.
+	FadeDistance float64
+	Fade         Falloff
+	FadeColor    Color
+
+	// Feather ramps the alpha of the dilated pixels down to transparent over
+	// the last this many pixels before the radius, or over this many pixels
+	// from the opaque areas without a radius, instead of a hard edge.
+	Feather float64
+
+	// Background fills the pixels that the dilation doesn't reach, beyond
+	// the radius or without any opaque pixel.
+	Background Background
+
+	// Spread is the distance in pixels from the edge to the ends of the
+	// range of SignedDistanceField, DefaultSpread if zero.
+	Spread float64
+
+	// MSDF makes SignedDistanceField output a multi-channel signed distance
+	// field, whose median of the color channels keeps corners sharp, for
+	// decals and fonts. The edges are the traced boundary of the coverage,
+	// with the image border outside, at Euclidean distances.
+	MSDF bool
+
+	// SDFSign is the side of the edge that SignedDistanceField encodes above
+	// 0.5, the inside by default.
+	SDFSign Sign
+
+	// SDFChannels selects the channels of SignedDistanceField that hold the
+	// field, RGB if zero. The other color channels are copied from the
+	// source and alpha is opaque. With MSDF, alpha holds the true distance.
+	SDFChannels Channels
+
+	// Channels selects the channels that are dilated, the others are copied
+	// from the source unchanged. Zero dilates all of them.
+	Channels Channels
+
+	// Premultiplied marks the colors of the image as premultiplied by alpha.
+	// They are divided by alpha before dilation and multiplied again in the
+	// result, so dark fringes aren't dilated.
+	Premultiplied bool
+
+	// Composite blends the color of partially transparent pixels below the
+	// alpha threshold over the dilated color by their alpha, instead of
+	// replacing it, which keeps soft edges intact.
+	Composite bool
+
+	// KeepAlpha copies the alpha channel of the source to the result, so that
+	// only the color is dilated and the original coverage is preserved.
+	KeepAlpha bool
+
+	// Threads limits the goroutines an algorithm runs at once. Zero uses
+	// GOMAXPROCS.
+	Threads int
+
+	// checkpoint is set by Job to pause the algorithm between units of work.
+	checkpoint func()
+}
+
+// threads returns the number of goroutines an algorithm runs at once.
+func (o Options) threads() int {
+	if o.Threads > 0 {
+		return o.Threads
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// yield gives a running Job the chance to end its time slice. Algorithms call
+// it regularly from the goroutine that called Process.
+func (o Options) yield() {
+	if o.checkpoint != nil {
+		o.checkpoint()
+	}
+}
+
+func (o Options) progress(fraction float64) {
+	if o.Progress != nil {
+		o.Progress(fraction)
+	}
+}
+
+func (o Options) logf(format string, args ...any) {
+	if o.Log != nil {
+		fmt.Fprintf(o.Log, format, args...)
+	}
+}
+
+// Algorithm dilates the opaque texels of an image into its transparent areas.
+type Algorithm interface {
+	Process(src Image, opts Options) Image
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[string]Algorithm{}
+)
+
+// Register makes an algorithm available by name. It panics if the name is
+// already taken, mirroring image.RegisterFormat and database/sql.Register.
+func Register(name string, alg Algorithm) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	if _, exists := algorithms[name]; exists {
+		panic("uvpad: algorithm " + name + " registered twice")
+	}
+	algorithms[name] = alg
+}
+
+// Lookup returns the algorithm registered under name.
+func Lookup(name string) (Algorithm, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	alg, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown algorithm %q, available: %v", name, algorithmNames())
+	}
+	return alg, nil
+}
+
+// Algorithms returns the sorted names of all registered algorithms.
+func Algorithms() []string {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	return algorithmNames()
+}
+
+func algorithmNames() []string {
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,177 @@
+package uvpad
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// atlasFrame is a rectangle of an atlas in the JSON formats: TexturePacker
+// frames, whose rectangle is nested in frame and swapped when rotated, or
+// plain rectangles.
+type atlasFrame struct {
+	Frame   *atlasRect
+	Rotated bool
+	atlasRect
+}
+
+type atlasRect struct {
+	X, Y, W, H int
+}
+
+// ParseAtlas reads the rectangles of a texture atlas, from JSON or CSV by
+// its first character. The JSON is either a TexturePacker export, with its
+// frames as an array or a hash, or an array of objects with x, y, w and h.
+// CSV rows hold x, y, w and h, optionally after a name, and a header row
+// is skipped.
+func ParseAtlas(r io.Reader) ([]image.Rectangle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return parseAtlasJSON(trimmed)
+	}
+	return parseAtlasCSV(data)
+}
+
+func parseAtlasJSON(data []byte) ([]image.Rectangle, error) {
+	var frames []atlasFrame
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &frames); err != nil {
+			return nil, fmt.Errorf("atlas: %w", err)
+		}
+	} else {
+		var doc struct {
+			Frames json.RawMessage
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("atlas: %w", err)
+		}
+		if len(doc.Frames) == 0 {
+			return nil, errors.New("atlas: missing frames")
+		}
+		if err := json.Unmarshal(doc.Frames, &frames); err != nil {
+			// The hash format maps the names to the frames, in the
+			// order they appear.
+			var hash map[string]atlasFrame
+			if err := json.Unmarshal(doc.Frames, &hash); err != nil {
+				return nil, fmt.Errorf("atlas: %w", err)
+			}
+			decoder := json.NewDecoder(bytes.NewReader(doc.Frames))
+			decoder.Token()
+			for decoder.More() {
+				name, _ := decoder.Token()
+				var skip json.RawMessage
+				decoder.Decode(&skip)
+				frames = append(frames, hash[name.(string)])
+			}
+		}
+	}
+
+	rects := make([]image.Rectangle, len(frames))
+	for i, f := range frames {
+		r := f.atlasRect
+		if f.Frame != nil {
+			r = *f.Frame
+			if f.Rotated {
+				r.W, r.H = r.H, r.W
+			}
+		}
+		rects[i] = image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+	}
+	return rects, nil
+}
+
+func parseAtlasCSV(data []byte) ([]image.Rectangle, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("atlas: %w", err)
+	}
+
+	var rects []image.Rectangle
+	for i, record := range records {
+		if len(record) > 4 {
+			record = record[len(record)-4:]
+		}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("atlas: row %d has %d fields, expected x, y, w and h", i+1, len(record))
+		}
+		var v [4]int
+		for j, field := range record {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				if i == 0 {
+					break
+				}
+				return nil, fmt.Errorf("atlas: row %d: invalid number %q", i+1, field)
+			}
+			v[j] = n
+			if j == 3 {
+				rects = append(rects, image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]))
+			}
+		}
+	}
+	return rects, nil
+}
+
+// AtlasIslands returns the island map of the rectangles of an atlas in a
+// width by height texture, each rectangle an island, so that they are
+// dilated independently. Later rectangles cover earlier ones.
+func AtlasIslands(width, height int, rects []image.Rectangle) *IslandMap {
+	m := &IslandMap{Width: width, Height: height, Islands: make([]int, width*height), Count: len(rects)}
+	for i := range m.Islands {
+		m.Islands[i] = -1
+	}
+	bounds := image.Rect(0, 0, width, height)
+	for i, r := range rects {
+		r = r.Intersect(bounds)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				m.Islands[y*width+x] = i
+			}
+		}
+	}
+	return m
+}
+
+// ParseGrid parses the columns and rows of a sprite sheet grid written as
+// CxR, such as 8x4.
+func ParseGrid(s string) (columns, rows int, err error) {
+	c, r, ok := strings.Cut(strings.ToLower(s), "x")
+	if ok {
+		columns, err = strconv.Atoi(c)
+	}
+	if ok && err == nil {
+		rows, err = strconv.Atoi(r)
+	}
+	if !ok || err != nil || columns <= 0 || rows <= 0 {
+		return 0, 0, fmt.Errorf("invalid grid %q, expected columns and rows such as 8x4", s)
+	}
+	return columns, rows, nil
+}
+
+// GridRects returns the cells of a uniform sprite sheet grid in a width by
+// height texture, row by row, with padding pixels between the cells.
+func GridRects(width, height, columns, rows, padding int) []image.Rectangle {
+	cellWidth := (width - (columns-1)*padding) / columns
+	cellHeight := (height - (rows-1)*padding) / rows
+	rects := make([]image.Rectangle, 0, columns*rows)
+	for row := range rows {
+		for column := range columns {
+			x, y := column*(cellWidth+padding), row*(cellHeight+padding)
+			rects = append(rects, image.Rect(x, y, x+cellWidth, y+cellHeight))
+		}
+	}
+	return rects
+}
@@ -0,0 +1,140 @@
+package uvpad
+
+import "math"
+
+func init() {
+	Register("auto", auto{})
+}
+
+// autoMaxPasses is the most passes for which auto picks gimp. A pass of gimp
+// costs about a fortieth of the whole push-pull pyramid, and its averages
+// streak as the passes grow.
+const autoMaxPasses = 32
+
+// autoCandidates are the algorithms auto picks from.
+var autoCandidates = []string{"gimp", "jfa", "pushpull"}
+
+// auto picks an algorithm for every image from its coverage: gimp when its
+// holes fill in a few passes, such as the gutters of sprite sheets and
+// padding limited by a radius; pushpull when most of it is empty and the
+// holes are large, such as lightmaps, whose charts need a smooth fill; and
+// jfa otherwise. Holes are measured in pixels, so larger textures of the
+// same layout lean away from gimp.
+type auto struct{}
+
+// EstimateMemory is the estimate of the candidate that needs the most, as
+// the coverage measured to pick it is released first.
+func (auto) EstimateMemory(width, height, sampleSize int) int64 {
+	var most int64
+	for _, name := range autoCandidates {
+		alg, _ := Lookup(name)
+		most = max(most, estimate(alg, width, height, sampleSize))
+	}
+	return most
+}
+
+func (a auto) Process(src Image, opts Options) Image {
+	if opts.changesCoverage() {
+		// Pick by the coverage that is dilated, such as that of a mask on an
+		// opaque lightmap.
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processCoverage(src, opts, a.Process)
+		case *Buffer[uint16]:
+			return processCoverage(src, opts, a.Process)
+		case *Buffer[float32]:
+			return processCoverage(src, opts, a.Process)
+		}
+	}
+
+	var name string
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		name = chooseAlgorithm(src, opts)
+	case *Buffer[uint16]:
+		name = chooseAlgorithm(src, opts)
+	case *Buffer[float32]:
+		name = chooseAlgorithm(src, opts)
+	}
+	opts.logf("Chose the %s algorithm\n", name)
+	alg, _ := Lookup(name)
+	return alg.Process(src, opts)
+}
+
+// chooseAlgorithm returns the name of the algorithm auto dilates input with.
+func chooseAlgorithm[T Sample](input *Buffer[T], opts Options) string {
+	if opts.Blend > 1 {
+		// Only the nearest seed algorithms blend seeds.
+		return "jfa"
+	}
+	opaqueMask := seedMask(input, opts)
+	defer maskPool.put(opaqueMask)
+
+	transparent := 0
+	for _, opaque := range opaqueMask {
+		if !opaque {
+			transparent++
+		}
+	}
+	if transparent == 0 || transparent == len(opaqueMask) {
+		return "jfa"
+	}
+
+	kernel := max(1, opts.Kernel)
+	hole := holeSize(input.Width, input.Height, opaqueMask, opts.Connectivity == 8)
+	if opts.Radius > 0 {
+		hole = min(hole, int(math.Ceil(opts.Radius)))
+	}
+	switch {
+	case (hole+kernel-1)/kernel <= autoMaxPasses:
+		return "gimp"
+	case transparent*2 >= len(opaqueMask):
+		return "pushpull"
+	default:
+		return "jfa"
+	}
+}
+
+// holeSize returns the distance from the transparent pixel farthest from the
+// seeds of opaqueMask to its nearest seed, in steps to the adjacent pixels
+// along the axes, or diagonally too, which is the number of passes gimp
+// needs. The mask must have a seed.
+func holeSize(width, height int, opaqueMask []bool, diagonal bool) int {
+	// A two pass chamfer transform: each pass carries the distances down and
+	// across from the neighbours it has already visited. Pixels no seed has
+	// reached yet stay unreached rather than wrapping around when stepped.
+	const unreached = math.MaxInt32
+	distances := seedPool.get(width * height)
+	defer seedPool.put(distances)
+	step := func(x, y int) int32 {
+		if x < 0 || x >= width || y < 0 || y >= height || distances[y*width+x] == unreached {
+			return unreached
+		}
+		return distances[y*width+x] + 1
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			d := int32(0)
+			if !opaqueMask[y*width+x] {
+				d = min(step(x-1, y), step(x, y-1))
+				if diagonal {
+					d = min(d, step(x-1, y-1), step(x+1, y-1))
+				}
+			}
+			distances[y*width+x] = d
+		}
+	}
+
+	hole := int32(0)
+	for y := height - 1; y >= 0; y-- {
+		for x := width - 1; x >= 0; x-- {
+			d := min(distances[y*width+x], step(x+1, y), step(x, y+1))
+			if diagonal {
+				d = min(d, step(x+1, y+1), step(x-1, y+1))
+			}
+			distances[y*width+x] = d
+			hole = max(hole, d)
+		}
+	}
+	return int(hole)
+}
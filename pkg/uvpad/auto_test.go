@@ -0,0 +1,22 @@
+package uvpad
+
+import "testing"
+
+func TestHoleSizeCornerSeed(t *testing.T) {
+	const size = 64
+	mask := make([]bool, size*size)
+	mask[size-1] = true // The top-right pixel.
+
+	if got := holeSize(size, size, mask, false); got != 2*(size-1) {
+		t.Errorf("holeSize with 4-connectivity = %d, want %d", got, 2*(size-1))
+	}
+	if got := holeSize(size, size, mask, true); got != size-1 {
+		t.Errorf("holeSize with 8-connectivity = %d, want %d", got, size-1)
+	}
+
+	b := NewBuffer[uint8](size, size)
+	b.Pix[(size-1)*4+3] = 255
+	if got := chooseAlgorithm(b, Options{Connectivity: 4}); got == "gimp" {
+		t.Errorf("chooseAlgorithm picked gimp for a hole of %d passes", 2*(size-1))
+	}
+}
@@ -0,0 +1,97 @@
+package uvpad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BackgroundMode selects how pixels that the dilation doesn't reach, beyond
+// the radius or without any opaque pixel, are filled.
+type BackgroundMode int
+
+const (
+	// BackgroundTransparent leaves them transparent.
+	BackgroundTransparent BackgroundMode = iota
+	// BackgroundAverage fills them with the average color of the opaque
+	// pixels.
+	BackgroundAverage
+	// BackgroundColor fills them with Background.Color.
+	BackgroundColor
+)
+
+// Background is the fill of the pixels that the dilation doesn't reach.
+type Background struct {
+	Mode  BackgroundMode
+	Color Color
+}
+
+// ParseBackground parses a background: transparent, average or
+// color:#RRGGBB (or #RRGGBBAA).
+func ParseBackground(s string) (Background, error) {
+	switch s {
+	case "transparent":
+		return Background{Mode: BackgroundTransparent}, nil
+	case "average":
+		return Background{Mode: BackgroundAverage}, nil
+	}
+	if hex, ok := strings.CutPrefix(s, "color:"); ok {
+		c, err := ParseColor(hex)
+		if err != nil {
+			return Background{}, err
+		}
+		return Background{Mode: BackgroundColor, Color: c}, nil
+	}
+	return Background{}, fmt.Errorf("unknown background %q, expected transparent, average or color:#RRGGBB", s)
+}
+
+func (b Background) String() string {
+	switch b.Mode {
+	case BackgroundTransparent:
+		return "transparent"
+	case BackgroundAverage:
+		return "average"
+	default:
+		return "color:" + b.Color.String()
+	}
+}
+
+// fillBackground fills the pixels of dst below the seed alpha, which the
+// algorithm didn't reach or feathered, with the background behind them.
+func fillBackground[T Sample](dst, src *Buffer[T], opts Options) {
+	seed := seedAlpha[T](opts)
+
+	color := opts.Background.Color
+	if opts.Background.Mode == BackgroundAverage {
+		var sum [3]float64
+		var count float64
+		for i := 0; i < len(src.Pix); i += 4 {
+			if src.Pix[i+3] >= seed {
+				for c := range sum {
+					sum[c] += normalize(src.Pix[i+c])
+				}
+				count++
+			}
+		}
+		if count == 0 {
+			return
+		}
+		color = Color{sum[0] / count, sum[1] / count, sum[2] / count, 1}
+	}
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if dst.Pix[i+3] >= seed {
+			continue
+		}
+		pixel := dst.Pix[i : i+4]
+		a := normalize(pixel[3])
+		alpha := a + color[3]*(1-a)
+		if alpha == 0 {
+			clear(pixel)
+			continue
+		}
+		for c := 0; c < 3; c++ {
+			pixel[c] = denormalize[T]((normalize(pixel[c])*a + color[c]*color[3]*(1-a)) / alpha)
+		}
+		pixel[3] = denormalize[T](alpha)
+	}
+}
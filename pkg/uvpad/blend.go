@@ -0,0 +1,151 @@
+package uvpad
+
+import "math"
+
+// kNearest returns the opts.Blend nearest seeds of every pixel, nearest
+// first, as consecutive runs of opts.Blend seeds padded with noSeed. The
+// lists start from the single nearest seeds and are refined with a jump flood
+// that merges the lists of neighbours, so the farther seeds are found among
+// the neighbouring Voronoi cells.
+func kNearest(width, height int, nearest []int32, opts Options) []int32 {
+	k := opts.Blend
+	lists := seedPool.get(len(nearest) * k)
+	for idx, seed := range nearest {
+		list := lists[idx*k : (idx+1)*k]
+		list[0] = seed
+		for i := 1; i < k; i++ {
+			list[i] = noSeed
+		}
+	}
+
+	step := 1
+	for step*2 < max(width, height) {
+		step *= 2
+	}
+	if opts.Radius > 0 {
+		step = min(step, int(math.Ceil(opts.Radius)))
+	}
+
+	previous := seedPool.get(len(lists))
+	for ; step >= 1; step /= 2 {
+		copy(previous, lists)
+		forBands(height, opts, func(start, end int) {
+			mergeNearest(width, height, previous, lists, step, start, end, opts)
+		})
+	}
+	seedPool.put(previous)
+
+	return lists
+}
+
+// mergeNearest updates the lists of rows start to end with the seeds listed
+// by the neighbours step pixels away.
+func mergeNearest(width, height int, previous, lists []int32, step, start, end int, opts Options) {
+	k := opts.Blend
+	edge := opts.Edge
+	distances := make([]int64, k)
+
+	for y := start; y < end; y++ {
+		for x := 0; x < width; x++ {
+			idx := y*width + x
+			list := lists[idx*k : (idx+1)*k]
+			for i, seed := range list {
+				distances[i] = math.MaxInt64
+				if seed != noSeed {
+					distances[i] = opts.Metric.distance(seedOffset(x, y, seed, width, height, edge))
+				}
+			}
+
+			for dy := -step; dy <= step; dy += step {
+				for dx := -step; dx <= step; dx += step {
+					nx, okx := edge.resolve(x+dx, width)
+					ny, oky := edge.resolve(y+dy, height)
+					if (dx == 0 && dy == 0) || !okx || !oky {
+						continue
+					}
+
+					for _, seed := range previous[(ny*width+nx)*k : (ny*width+nx+1)*k] {
+						if seed == noSeed {
+							break
+						}
+						d := opts.Metric.distance(seedOffset(x, y, seed, width, height, edge))
+						insertNearest(list, distances, seed, d)
+					}
+				}
+			}
+		}
+	}
+}
+
+// insertNearest inserts the seed p at distance d into a list sorted by
+// distance, dropping the farthest entry, unless p is already listed or too
+// far.
+func insertNearest(list []int32, distances []int64, p int32, d int64) {
+	last := len(list) - 1
+	if d >= distances[last] {
+		return
+	}
+	for _, q := range list {
+		if q == p {
+			return
+		}
+	}
+
+	i := last
+	for ; i > 0 && distances[i-1] > d; i-- {
+		list[i], distances[i] = list[i-1], distances[i-1]
+	}
+	list[i], distances[i] = p, d
+}
+
+// blendNearest returns a copy of input where every pixel outside the seed
+// mask takes the average color of its listed seeds within the radius,
+// weighted by inverse distance, or is cleared if it has none.
+func blendNearest[T Sample](input *Buffer[T], opaqueMask []bool, lists []int32, opts Options) *Buffer[T] {
+	width, height := input.Width, input.Height
+	k := opts.Blend
+	opaque := opaqueValue[T]()
+
+	output := input.Clone()
+	limit := opts.Metric.limit(opts.Radius)
+	for idx := range opaqueMask {
+		if opaqueMask[idx] {
+			continue
+		}
+
+		var color [3]float64
+		var total float64
+		for _, p := range lists[idx*k : (idx+1)*k] {
+			if p == noSeed {
+				break
+			}
+			dx, dy := seedOffset(idx%width, idx/width, p, width, height, opts.Edge)
+			if !opts.Metric.within(dx, dy, limit) {
+				continue
+			}
+
+			distance := float64(opts.Metric.distance(dx, dy))
+			if opts.Metric == MetricEuclidean {
+				distance = math.Sqrt(distance)
+			}
+			weight := 1 / distance
+			seed := input.Pix[int(p)*4:]
+			for c := range color {
+				color[c] += weight * normalize(seed[c])
+			}
+			total += weight
+		}
+
+		pixel := output.Pix[idx*4 : idx*4+4]
+		if total == 0 {
+			clear(pixel)
+			continue
+		}
+		for c := range color {
+			pixel[c] = denormalize[T](color[c] / total)
+		}
+		pixel[3] = opaque
+	}
+
+	return output
+}
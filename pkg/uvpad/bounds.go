@@ -0,0 +1,79 @@
+package uvpad
+
+import "image"
+
+// transparentBounds returns the bounds of the pixels outside opaqueMask,
+// the only ones a dilation fills, expanded by the margin of the radius that
+// holds every seed within the radius of them. It is empty if every pixel is
+// a seed.
+func transparentBounds(width, height int, opaqueMask []bool, opts Options) image.Rectangle {
+	var bounds image.Rectangle
+	for y := 0; y < height; y++ {
+		row := opaqueMask[y*width : (y+1)*width]
+		first := -1
+		for x, opaque := range row {
+			if !opaque {
+				first = x
+				break
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		last := first
+		for x := width - 1; x > first; x-- {
+			if !row[x] {
+				last = x
+				break
+			}
+		}
+		bounds = bounds.Union(image.Rect(first, y, last+1, y+1))
+	}
+	if bounds.Empty() {
+		return bounds
+	}
+	return bounds.Inset(-opts.tileMargin()).Intersect(image.Rect(0, 0, width, height))
+}
+
+// boundedTransform runs transform only within the transparent bounds of
+// opaqueMask when a radius limits the reach of the seeds, so that images
+// that are mostly opaque, such as packed atlases, don't pay for the whole
+// area. Every pixel outside the bounds is its own nearest seed.
+func boundedTransform(width, height int, opaqueMask []bool, opts Options, transform func(width, height int, opaqueMask []bool, opts Options) []int32) []int32 {
+	// With EdgeWrap the seeds across the border count as well.
+	if opts.Radius <= 0 || opts.Edge == EdgeWrap {
+		return transform(width, height, opaqueMask, opts)
+	}
+	bounds := transparentBounds(width, height, opaqueMask, opts)
+	if bounds == image.Rect(0, 0, width, height) {
+		return transform(width, height, opaqueMask, opts)
+	}
+
+	nearest := seedPool.get(width * height)
+	for idx := range nearest {
+		nearest[idx] = int32(idx)
+	}
+	if bounds.Empty() {
+		return nearest
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	mask := maskPool.get(w * h)
+	for y := range h {
+		offset := (bounds.Min.Y+y)*width + bounds.Min.X
+		copy(mask[y*w:(y+1)*w], opaqueMask[offset:offset+w])
+	}
+	cropped := transform(w, h, mask, opts)
+	for y := range h {
+		for x := range w {
+			seed := cropped[y*w+x]
+			if seed != noSeed {
+				seed = int32((bounds.Min.Y+int(seed)/w)*width + bounds.Min.X + int(seed)%w)
+			}
+			nearest[(bounds.Min.Y+y)*width+bounds.Min.X+x] = seed
+		}
+	}
+	maskPool.put(mask)
+	seedPool.put(cropped)
+	return nearest
+}
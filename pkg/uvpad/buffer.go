@@ -0,0 +1,466 @@
+package uvpad
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Sample is the type of a single channel value. Integer samples span zero to
+// their maximum value, float32 samples span 0 to 1 but may exceed 1 for HDR
+// data.
+type Sample interface {
+	uint8 | uint16 | float32
+}
+
+// Buffer is a non-premultiplied RGBA image with four interleaved channels per
+// pixel, stored row by row without padding.
+type Buffer[T Sample] struct {
+	Pix           []T
+	Width, Height int
+
+	// borrowed is set when Pix is shared with an image, so it is never
+	// returned to the pools.
+	borrowed bool
+}
+
+// NewBuffer allocates a transparent black buffer.
+func NewBuffer[T Sample](width, height int) *Buffer[T] {
+	pix := pixelPool[T]().get(width * height * 4)
+	clear(pix)
+	return &Buffer[T]{
+		Pix:    pix,
+		Width:  width,
+		Height: height,
+	}
+}
+
+// Size returns the dimensions of the buffer.
+func (b *Buffer[T]) Size() (width, height int) {
+	return b.Width, b.Height
+}
+
+// Clone returns a deep copy of the buffer.
+func (b *Buffer[T]) Clone() *Buffer[T] {
+	return &Buffer[T]{
+		Pix:    append(pixelPool[T]().get(len(b.Pix))[:0], b.Pix...),
+		Width:  b.Width,
+		Height: b.Height,
+	}
+}
+
+// Image converts the buffer to a standard library image: *image.NRGBA for
+// 8-bit buffers and *image.NRGBA64 otherwise. Float samples are clamped. An
+// *image.NRGBA shares the pixels of the buffer rather than copying them.
+func (b *Buffer[T]) Image() image.Image {
+	rect := image.Rect(0, 0, b.Width, b.Height)
+	if pix, ok := any(b.Pix).([]uint8); ok {
+		b.borrowed = true
+		return &image.NRGBA{Pix: pix, Stride: b.Width * 4, Rect: rect}
+	}
+
+	img := image.NewNRGBA64(rect)
+	for i, v := range b.Pix {
+		s := denormalize[uint16](normalize(v))
+		img.Pix[i*2] = uint8(s >> 8)
+		img.Pix[i*2+1] = uint8(s)
+	}
+	return img
+}
+
+// Image is a *Buffer of any supported sample type.
+type Image interface {
+	Size() (width, height int)
+	Image() image.Image
+}
+
+// FromImage converts a standard library image into a buffer whose depth
+// matches the source: 16-bit images become *Buffer[uint16], a FloatImage
+// *Buffer[float32], everything else *Buffer[uint8]. The image types of the standard decoders are read
+// from their Pix slices directly, others pixel by pixel. An *image.NRGBA
+// whose rows are contiguous, and an *image.RGBA whose pixels are all opaque
+// or transparent black, which are the same in both, and a FloatImage share
+// their pixels with the buffer instead, so they must not change while it is
+// in use.
+func FromImage(img image.Image) Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	switch img := img.(type) {
+	case FloatImage:
+		return &Buffer[float32]{Pix: img.Pix, Width: width, Height: height, borrowed: true}
+	case *image.NRGBA:
+		if img.Stride == width*4 {
+			return sharedBuffer(img.Pix, img.PixOffset(bounds.Min.X, bounds.Min.Y), width, height)
+		}
+		buf := NewBuffer[uint8](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			copy(buf.Pix[y*width*4:(y+1)*width*4], row)
+		}
+		return buf
+	case *image.RGBA:
+		if img.Stride == width*4 && straightAlpha(img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y):][:width*height*4]) {
+			return sharedBuffer(img.Pix, img.PixOffset(bounds.Min.X, bounds.Min.Y), width, height)
+		}
+		buf := NewBuffer[uint8](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := range width {
+				p, q := row[x*4:x*4+4], buf.Pix[(y*width+x)*4:][:4]
+				// Unpremultiplied at 16 bits, as color.NRGBAModel rounds.
+				a := uint32(p[3]) * 0x101
+				for c := range 3 {
+					q[c] = uint8(unpremultiply(uint32(p[c])*0x101, a) >> 8)
+				}
+				q[3] = p[3]
+			}
+		}
+		return buf
+	case *image.Gray:
+		buf := NewBuffer[uint8](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := range width {
+				q := buf.Pix[(y*width+x)*4:][:4]
+				q[0], q[1], q[2], q[3] = row[x], row[x], row[x], math.MaxUint8
+			}
+		}
+		return buf
+	case *image.YCbCr:
+		buf := NewBuffer[uint8](width, height)
+		for y := range height {
+			for x := range width {
+				r, g, b, _ := img.YCbCrAt(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				q := buf.Pix[(y*width+x)*4:][:4]
+				q[0], q[1], q[2], q[3] = uint8(r>>8), uint8(g>>8), uint8(b>>8), math.MaxUint8
+			}
+		}
+		return buf
+	case *image.NRGBA64:
+		buf := NewBuffer[uint16](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for i := range width * 4 {
+				buf.Pix[y*width*4+i] = uint16(row[i*2])<<8 | uint16(row[i*2+1])
+			}
+		}
+		return buf
+	case *image.RGBA64:
+		buf := NewBuffer[uint16](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := range width {
+				p, q := row[x*8:x*8+8], buf.Pix[(y*width+x)*4:][:4]
+				a := uint32(p[6])<<8 | uint32(p[7])
+				for c := range 3 {
+					q[c] = uint16(unpremultiply(uint32(p[c*2])<<8|uint32(p[c*2+1]), a))
+				}
+				q[3] = uint16(a)
+			}
+		}
+		return buf
+	case *image.Gray16:
+		buf := NewBuffer[uint16](width, height)
+		for y := range height {
+			row := img.Pix[img.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			for x := range width {
+				v := uint16(row[x*2])<<8 | uint16(row[x*2+1])
+				q := buf.Pix[(y*width+x)*4:][:4]
+				q[0], q[1], q[2], q[3] = v, v, v, math.MaxUint16
+			}
+		}
+		return buf
+	default:
+		return fromImage[uint8](img, func(c color.Color) [4]uint8 {
+			n := color.NRGBAModel.Convert(c).(color.NRGBA)
+			return [4]uint8{n.R, n.G, n.B, n.A}
+		})
+	}
+}
+
+// sharedBuffer returns a buffer of the contiguous width×height pixels of pix
+// from offset.
+func sharedBuffer(pix []uint8, offset, width, height int) *Buffer[uint8] {
+	return &Buffer[uint8]{Pix: pix[offset:][:width*height*4], Width: width, Height: height, borrowed: true}
+}
+
+// straightAlpha reports whether the premultiplied pixels of pix are the same
+// unpremultiplied: every pixel is opaque or transparent black.
+func straightAlpha(pix []uint8) bool {
+	for i := 0; i < len(pix); i += 4 {
+		if a := pix[i+3]; a != math.MaxUint8 && (a != 0 || pix[i]|pix[i+1]|pix[i+2] != 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// unpremultiply divides a 16-bit premultiplied channel by a 16-bit alpha as
+// the color models of the standard library do.
+func unpremultiply(v, alpha uint32) uint32 {
+	switch alpha {
+	case 0:
+		return 0
+	case 0xffff:
+		return v
+	}
+	return v * 0xffff / alpha
+}
+
+func fromImage[T Sample](img image.Image, convert func(color.Color) [4]T) *Buffer[T] {
+	bounds := img.Bounds()
+	buf := NewBuffer[T](bounds.Dx(), bounds.Dy())
+	for y := 0; y < buf.Height; y++ {
+		for x := 0; x < buf.Width; x++ {
+			c := convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+			copy(buf.Pix[(y*buf.Width+x)*4:], c[:])
+		}
+	}
+	return buf
+}
+
+// Convert changes the sample type of a buffer, rescaling values so that the
+// maximum of one type maps to the maximum of the other.
+func Convert[To, From Sample](b *Buffer[From]) *Buffer[To] {
+	out := NewBuffer[To](b.Width, b.Height)
+	for i, v := range b.Pix {
+		out.Pix[i] = denormalize[To](normalize(v))
+	}
+	return out
+}
+
+// opaqueValue is the alpha value of a fully opaque pixel.
+func opaqueValue[T Sample]() T {
+	var v T
+	switch p := any(&v).(type) {
+	case *uint8:
+		*p = math.MaxUint8
+	case *uint16:
+		*p = math.MaxUint16
+	case *float32:
+		*p = 1
+	}
+	return v
+}
+
+// seedAlpha is the minimum alpha of the pixels that are dilated.
+func seedAlpha[T Sample](opts Options) T {
+	if opts.AlphaThreshold <= 0 {
+		return opaqueValue[T]()
+	}
+	return denormalize[T](opts.AlphaThreshold)
+}
+
+// normalize maps a sample to the 0..1 range (or beyond for HDR floats).
+func normalize[T Sample](v T) float64 {
+	return float64(v) / float64(opaqueValue[T]())
+}
+
+// denormalize maps a 0..1 value to a sample, rounding and clamping integer
+// types.
+func denormalize[T Sample](v float64) T {
+	max := float64(opaqueValue[T]())
+	if max == 1 {
+		return T(v)
+	}
+	return T(math.Round(math.Max(0, math.Min(1, v)) * max))
+}
+
+// generic adapts a dilation function written once over Sample into an
+// Algorithm by instantiating it for every supported depth.
+type generic struct {
+	u8  func(*Buffer[uint8], Options) *Buffer[uint8]
+	u16 func(*Buffer[uint16], Options) *Buffer[uint16]
+	f32 func(*Buffer[float32], Options) *Buffer[float32]
+
+	// scratch is the number of bytes per pixel the algorithm allocates on
+	// top of its input and output buffers, given the bytes per pixel of one
+	// buffer.
+	scratch func(bufferBytes int64) int64
+}
+
+func (g generic) EstimateMemory(width, height, sampleSize int) int64 {
+	pixels := int64(width) * int64(height)
+	bufferBytes := 4 * int64(sampleSize)
+	return pixels * (2*bufferBytes + g.scratch(bufferBytes))
+}
+
+func (g generic) Process(src Image, opts Options) Image {
+	if opts.Packed {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processPacked(src, opts, g.Process)
+		case *Buffer[uint16]:
+			return processPacked(src, opts, g.Process)
+		case *Buffer[float32]:
+			return processPacked(src, opts, g.Process)
+		}
+	}
+	if opts.Premultiplied {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processPremultiplied(src, opts, g.Process)
+		case *Buffer[uint16]:
+			return processPremultiplied(src, opts, g.Process)
+		case *Buffer[float32]:
+			return processPremultiplied(src, opts, g.Process)
+		}
+	}
+	if opts.changesCoverage() {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processCoverage(src, opts, g.Process)
+		case *Buffer[uint16]:
+			return processCoverage(src, opts, g.Process)
+		case *Buffer[float32]:
+			return processCoverage(src, opts, g.Process)
+		}
+	}
+	if opts.Islands != nil {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processIslands(src, opts, g.Process)
+		case *Buffer[uint16]:
+			return processIslands(src, opts, g.Process)
+		case *Buffer[float32]:
+			return processIslands(src, opts, g.Process)
+		}
+	}
+
+	var dst Image
+	if width, height := src.Size(); opts.tiled(width, height) {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			out := NewBuffer[uint8](width, height)
+			processTiles(src, out, opts, g.process)
+			dst = out
+		case *Buffer[uint16]:
+			out := NewBuffer[uint16](width, height)
+			processTiles(src, out, opts, g.process)
+			dst = out
+		case *Buffer[float32]:
+			out := NewBuffer[float32](width, height)
+			processTiles(src, out, opts, g.process)
+			dst = out
+		}
+	} else {
+		dst = g.process(src, opts)
+	}
+	switch dst := dst.(type) {
+	case *Buffer[uint8]:
+		finish(dst, src.(*Buffer[uint8]), opts)
+	case *Buffer[uint16]:
+		finish(dst, src.(*Buffer[uint16]), opts)
+	case *Buffer[float32]:
+		finish(dst, src.(*Buffer[float32]), opts)
+	}
+	return dst
+}
+
+func (g generic) process(src Image, opts Options) Image {
+	if opts.NormalMap && opts.Bias == 0 {
+		// Normal maps store vectors from -1 to 1 biased by 0.5.
+		opts.Bias = 0.5
+	}
+	if opts.Bias != 0 {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processSigned(src, opts, g.f32)
+		case *Buffer[uint16]:
+			return processSigned(src, opts, g.f32)
+		case *Buffer[float32]:
+			return processSigned(src, opts, g.f32)
+		}
+	}
+	if opts.ColorSpace == ColorSpaceLinear {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processLinear(src, opts, g.f32)
+		case *Buffer[uint16]:
+			return processLinear(src, opts, g.f32)
+		case *Buffer[float32]:
+			return processLinear(src, opts, g.f32)
+		}
+	}
+
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return g.u8(src, opts)
+	case *Buffer[uint16]:
+		return g.u16(src, opts)
+	case *Buffer[float32]:
+		return g.f32(src, opts)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+// processSigned runs a float dilation on the signed decoding of a biased
+// buffer. A bias of 0.5 maps the stored 0..1 range to -1..1.
+func processSigned[T Sample](src *Buffer[T], opts Options, process func(*Buffer[float32], Options) *Buffer[float32]) *Buffer[T] {
+	bias := opts.Bias
+	scale := 1 / math.Max(bias, 1-bias)
+
+	signed := NewBuffer[float32](src.Width, src.Height)
+	for i, v := range src.Pix {
+		if i%4 == 3 {
+			signed.Pix[i] = float32(normalize(v))
+		} else {
+			signed.Pix[i] = float32((normalize(v) - bias) * scale)
+		}
+	}
+
+	dilated := process(signed, opts)
+	if opts.NormalMap {
+		renormalize(dilated, signed, seedAlpha[float32](opts), opts.NormalZ)
+	}
+
+	out := NewBuffer[T](src.Width, src.Height)
+	for i, v := range dilated.Pix {
+		if i%4 == 3 {
+			out.Pix[i] = denormalize[T](float64(v))
+		} else {
+			out.Pix[i] = denormalize[T](float64(v)/scale + bias)
+		}
+	}
+	if dilated != signed {
+		dilated.release()
+	}
+	signed.release()
+	return out
+}
+
+// finish applies the options that post-process the result of every
+// algorithm, in place on dst.
+func finish[T Sample](dst, src *Buffer[T], opts Options) {
+	if opts.FadeDistance > 0 {
+		fade(dst, src, opts)
+	}
+	if opts.Feather > 0 {
+		feather(dst, src, opts)
+	}
+	if opts.Background.Mode != BackgroundTransparent {
+		fillBackground(dst, src, opts)
+	}
+	if opts.Composite {
+		compositeOver(dst, src, seedAlpha[T](opts))
+	}
+	if kept := opts.kept(); kept != 0 {
+		copyChannels(dst, src, kept)
+	}
+}
+
+// compositeOver blends the color of the partially transparent source pixels
+// below seed over dst by their alpha.
+func compositeOver[T Sample](dst, src *Buffer[T], seed T) {
+	for i := 0; i < len(dst.Pix); i += 4 {
+		alpha := src.Pix[i+3]
+		if alpha == 0 || alpha >= seed {
+			continue
+		}
+		a := normalize(alpha)
+		for c := i; c < i+3; c++ {
+			dst.Pix[c] = denormalize[T](a*normalize(src.Pix[c]) + (1-a)*normalize(dst.Pix[c]))
+		}
+	}
+}
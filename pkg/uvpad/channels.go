@@ -0,0 +1,74 @@
+package uvpad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Channels is a set of RGBA channels.
+type Channels uint8
+
+// The single channels, and the common sets of them.
+const (
+	ChannelR Channels = 1 << iota
+	ChannelG
+	ChannelB
+	ChannelA
+
+	ChannelsRGB  = ChannelR | ChannelG | ChannelB
+	ChannelsRGBA = ChannelsRGB | ChannelA
+)
+
+const channelNames = "rgba"
+
+// ParseChannels parses a set of channels written as letters, such as rgb or
+// rgba, or separated by commas, such as r,g,b.
+func ParseChannels(s string) (Channels, error) {
+	var channels Channels
+	for _, r := range strings.ReplaceAll(strings.ToLower(s), ",", "") {
+		i := strings.IndexRune(channelNames, r)
+		if i < 0 {
+			return 0, fmt.Errorf("invalid channels %q, expected letters of rgba such as rgb or r,g,b", s)
+		}
+		channels |= 1 << i
+	}
+	if channels == 0 {
+		return 0, fmt.Errorf("invalid channels %q, expected at least one of r, g, b or a", s)
+	}
+	return channels, nil
+}
+
+func (c Channels) String() string {
+	var s strings.Builder
+	for i := range channelNames {
+		if c&(1<<i) != 0 {
+			s.WriteByte(channelNames[i])
+		}
+	}
+	return s.String()
+}
+
+// kept returns the channels of the source that are copied to the result
+// instead of being dilated.
+func (o Options) kept() Channels {
+	var kept Channels
+	if o.Channels != 0 {
+		kept = ChannelsRGBA &^ o.Channels
+	}
+	if o.KeepAlpha {
+		kept |= ChannelA
+	}
+	return kept
+}
+
+// copyChannels replaces the given channels of dst with those of src.
+func copyChannels[T Sample](dst, src *Buffer[T], channels Channels) {
+	for c := range 4 {
+		if channels&(1<<c) == 0 {
+			continue
+		}
+		for i := c; i < len(dst.Pix); i += 4 {
+			dst.Pix[i] = src.Pix[i]
+		}
+	}
+}
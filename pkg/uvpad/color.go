@@ -0,0 +1,36 @@
+package uvpad
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color is a non-premultiplied RGBA color with channels from 0 to 1.
+type Color [4]float64
+
+// ParseColor parses a hex color, #RRGGBB or #RRGGBBAA. Colors without alpha
+// are opaque.
+func ParseColor(s string) (Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 6 && len(hex) != 8) {
+		return Color{}, fmt.Errorf("invalid color %q, expected #RRGGBB or #RRGGBBAA", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	var c Color
+	for i := range c {
+		v, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return Color{}, fmt.Errorf("invalid color %q, expected #RRGGBB or #RRGGBBAA", s)
+		}
+		c[i] = float64(v) / 255
+	}
+	return c, nil
+}
+
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", denormalize[uint8](c[0]), denormalize[uint8](c[1]), denormalize[uint8](c[2]), denormalize[uint8](c[3]))
+}
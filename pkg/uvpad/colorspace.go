@@ -0,0 +1,80 @@
+package uvpad
+
+import (
+	"fmt"
+	"math"
+)
+
+// ColorSpace is the space in which algorithms blend colors.
+type ColorSpace int
+
+const (
+	// ColorSpaceSRGB blends the stored values as they are.
+	ColorSpaceSRGB ColorSpace = iota
+	// ColorSpaceLinear decodes sRGB colors to linear light before dilation
+	// and encodes the result again, so averages don't darken.
+	ColorSpaceLinear
+)
+
+var colorSpaceNames = []string{"srgb", "linear"}
+
+func (c ColorSpace) String() string {
+	if int(c) < len(colorSpaceNames) {
+		return colorSpaceNames[c]
+	}
+	return fmt.Sprintf("ColorSpace(%d)", int(c))
+}
+
+// ParseColorSpace returns the color space with the given name: srgb or
+// linear.
+func ParseColorSpace(name string) (ColorSpace, error) {
+	for i, n := range colorSpaceNames {
+		if n == name {
+			return ColorSpace(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color space %q, expected srgb or linear", name)
+}
+
+// processLinear runs a float dilation on the linear light decoding of an
+// sRGB buffer.
+func processLinear[T Sample](src *Buffer[T], opts Options, process func(*Buffer[float32], Options) *Buffer[float32]) *Buffer[T] {
+	linear := NewBuffer[float32](src.Width, src.Height)
+	for i, v := range src.Pix {
+		if i%4 == 3 {
+			linear.Pix[i] = float32(normalize(v))
+		} else {
+			linear.Pix[i] = float32(srgbToLinear(normalize(v)))
+		}
+	}
+
+	dilated := process(linear, opts)
+
+	out := NewBuffer[T](src.Width, src.Height)
+	for i, v := range dilated.Pix {
+		if i%4 == 3 {
+			out.Pix[i] = denormalize[T](float64(v))
+		} else {
+			out.Pix[i] = denormalize[T](linearToSRGB(float64(v)))
+		}
+	}
+	if dilated != linear {
+		dilated.release()
+	}
+	linear.release()
+	return out
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
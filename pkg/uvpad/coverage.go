@@ -0,0 +1,82 @@
+package uvpad
+
+import "math"
+
+// changesCoverage reports whether the options change which pixels are seeds
+// before the dilation.
+func (o Options) changesCoverage() bool {
+	return o.Seed != SeedAlpha || o.KeyColor != nil || o.Mask != nil || o.Invert || o.Open > 0 || o.Close > 0 || o.Erode > 0
+}
+
+// processCoverage runs process on the copy of src with the coverage of
+// applyCoverage.
+func processCoverage[T Sample](src *Buffer[T], opts Options, process func(Image, Options) Image) *Buffer[T] {
+	// Kept channels come from src, not the covered copy.
+	kept := opts.kept()
+	covered, opts := applyCoverage(src, opts)
+	opts.KeepAlpha, opts.Channels = false, 0
+	out := process(covered, opts).(*Buffer[T])
+	if out != covered {
+		covered.release()
+	}
+	copyChannels(out, src, kept)
+	return out
+}
+
+// applyCoverage returns a copy of src whose alpha channel is the coverage
+// that decides which pixels are seeds: that of opts.Mask, the pixels of src
+// not matching opts.KeyColor, the luminance of src above opts.Threshold with
+// SeedLuminance or the alpha of src, inverted with opts.Invert, then opened,
+// closed and eroded. The returned options no longer change the coverage.
+func applyCoverage[T Sample](src *Buffer[T], opts Options) (*Buffer[T], Options) {
+	covered := src.Clone()
+	if opts.Seed == SeedLuminance {
+		luminanceCoverage(covered, opts.Threshold)
+	}
+	if opts.KeyColor != nil {
+		keyCoverage(covered, *opts.KeyColor, opts.KeyTolerance)
+	}
+	if opts.Mask != nil {
+		for idx, v := range maskCoverage(opts.Mask, src.Width, src.Height) {
+			covered.Pix[idx*4+3] = denormalize[T](v)
+		}
+	}
+	if opts.Invert {
+		for i := 3; i < len(covered.Pix); i += 4 {
+			covered.Pix[i] = denormalize[T](1 - normalize(covered.Pix[i]))
+		}
+	}
+	if opts.Open > 0 {
+		opening(covered, opts.Open, opts)
+	}
+	if opts.Close > 0 {
+		closing(covered, opts.Close, opts)
+	}
+	if opts.Erode > 0 {
+		erode(covered, opts.Erode, opts)
+	}
+
+	opts.Seed, opts.KeyColor, opts.Mask, opts.Invert = SeedAlpha, nil, nil, false
+	opts.Open, opts.Close, opts.Erode = 0, 0, 0
+	return covered, opts
+}
+
+// keyCoverage makes the pixels of b within tolerance of key on every color
+// channel transparent, and every other pixel opaque.
+func keyCoverage[T Sample](b *Buffer[T], key Color, tolerance float64) {
+	opaque := opaqueValue[T]()
+	for i := 0; i < len(b.Pix); i += 4 {
+		keyed := true
+		for c := 0; c < 3; c++ {
+			if math.Abs(normalize(b.Pix[i+c])-key[c]) > tolerance {
+				keyed = false
+				break
+			}
+		}
+		if keyed {
+			b.Pix[i+3] = 0
+		} else {
+			b.Pix[i+3] = opaque
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package uvpad
+
+func init() {
+	Register("diffusion", generic{
+		processDiffusion[uint8], processDiffusion[uint16], processDiffusion[float32],
+		func(int64) int64 {
+			// The pyramid of weighted colors, 4/3 of the image at 16 bytes
+			// per pixel, its known pixels, and a copy of the level being
+			// relaxed.
+			return 22 + 2 + 16
+		},
+	})
+}
+
+// DefaultIterations is the number of relaxation iterations per level of the
+// diffusion algorithm when Options.Iterations is zero.
+const DefaultIterations = 32
+
+// processDiffusion fills the transparent areas with the solution of the heat
+// equation with the opaque pixels held fixed, so colors interpolate smoothly
+// across the gaps. It solves coarse to fine on the push-pull pyramid: every
+// level starts from the upsampled solution of the coarser one and is relaxed
+// with Jacobi iterations.
+func processDiffusion[T Sample](input *Buffer[T], opts Options) *Buffer[T] {
+	opaqueMask := seedMask(input, opts)
+	levels := pyramid(seedLevel(input, opaqueMask), opts)
+
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = DefaultIterations
+	}
+
+	for i := len(levels) - 2; i >= 0; i-- {
+		known := maskPool.get(levels[i].width * levels[i].height)
+		for idx := range known {
+			known[idx] = levels[i].pix[idx*4+3] > 0
+		}
+
+		pull(levels[i], levels[i+1], opts)
+		relax(levels[i], known, iterations, opts)
+		maskPool.put(known)
+		opts.progress(float64(len(levels)-1-i) / float64(len(levels)-1))
+	}
+
+	output := fillLevel(input, opaqueMask, levels[0], opts)
+	releaseLevels(levels)
+	maskPool.put(opaqueMask)
+	opts.progress(1)
+	return output
+}
+
+// relax replaces the unknown pixels of l by the average of their 4-neighbours,
+// iterations times.
+func relax(l level, known []bool, iterations int, opts Options) {
+	previous := float32Pool.get(len(l.pix))
+	defer float32Pool.put(previous)
+	neighbours := []struct{ dx, dy int }{
+		{-1, 0}, {1, 0}, {0, -1}, {0, 1},
+	}
+
+	for range iterations {
+		copy(previous, l.pix)
+		forBands(l.height, opts, func(start, end int) {
+			for y := start; y < end; y++ {
+				for x := 0; x < l.width; x++ {
+					idx := y*l.width + x
+					if known[idx] {
+						continue
+					}
+
+					var sum [4]float32
+					var count float32
+					for _, n := range neighbours {
+						nx, okx := opts.Edge.resolve(x+n.dx, l.width)
+						ny, oky := opts.Edge.resolve(y+n.dy, l.height)
+						if okx && oky {
+							for c, v := range previous[(ny*l.width+nx)*4:][:4] {
+								sum[c] += v
+							}
+							count++
+						}
+					}
+					if count > 0 {
+						for c, v := range sum {
+							l.pix[idx*4+c] = v / count
+						}
+					}
+				}
+			}
+		})
+	}
+}
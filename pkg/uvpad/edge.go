@@ -0,0 +1,74 @@
+package uvpad
+
+import "fmt"
+
+// Edge selects how algorithms treat neighbours beyond the border of the image.
+type Edge int
+
+const (
+	// EdgeSkip ignores neighbours beyond the border.
+	EdgeSkip Edge = iota
+	// EdgeWrap treats the image as tiling, so that colors are dilated across
+	// its edges onto the opposite side.
+	EdgeWrap
+	// EdgeMirror reflects lookups at the border, as for symmetric trim
+	// sheets.
+	EdgeMirror
+)
+
+var edgeNames = []string{"skip", "wrap", "mirror"}
+
+func (e Edge) String() string {
+	if int(e) < len(edgeNames) {
+		return edgeNames[e]
+	}
+	return fmt.Sprintf("Edge(%d)", int(e))
+}
+
+// ParseEdge returns the edge mode with the given name: skip, wrap or mirror.
+func ParseEdge(name string) (Edge, error) {
+	for i, n := range edgeNames {
+		if n == name {
+			return Edge(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown edge mode %q, expected skip, wrap or mirror", name)
+}
+
+// resolve maps a coordinate along an axis of the given size into the image,
+// reporting false if it is beyond the border with EdgeSkip.
+func (e Edge) resolve(v, size int) (int, bool) {
+	if v >= 0 && v < size {
+		return v, true
+	}
+	switch e {
+	case EdgeWrap:
+		return (v%size + size) % size, true
+	case EdgeMirror:
+		if size == 1 {
+			return 0, true
+		}
+		period := 2 * (size - 1)
+		v = (v%period + period) % period
+		if v >= size {
+			v = period - v
+		}
+		return v, true
+	}
+	return v, false
+}
+
+// offset returns the shortest offset d between two coordinates along an axis
+// of the given size, which with EdgeWrap may cross the border of the image.
+func (e Edge) offset(d, size int) int {
+	if e != EdgeWrap {
+		return d
+	}
+	d %= size
+	if d > size/2 {
+		d -= size
+	} else if d < -size/2 {
+		d += size
+	}
+	return d
+}
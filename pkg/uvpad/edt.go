@@ -0,0 +1,191 @@
+package uvpad
+
+import (
+	"sync"
+	"unsafe"
+)
+
+func init() {
+	Register("edt", nearestAlgorithm{generic{
+		processEDT[uint8], processEDT[uint16], processEDT[float32],
+		func(int64) int64 {
+			// Seed mask, nearest seeds and the column distances.
+			return 1 + 2*int64(unsafe.Sizeof(int32(0)))
+		},
+	}, distanceTransform})
+}
+
+// processEDT fills every transparent pixel with the color of its exact
+// nearest opaque pixel, found with the separable distance transform of
+// Felzenszwalb-Huttenlocher and Meijster et al. It is slower than the jump
+// flood but never picks a wrong seed.
+func processEDT[T Sample](input *Buffer[T], opts Options) *Buffer[T] {
+	opaqueMask := seedMask(input, opts)
+	nearest := boundedTransform(input.Width, input.Height, opaqueMask, opts, distanceTransform)
+	output := fillNearest(input, opaqueMask, nearest, opts)
+	maskPool.put(opaqueMask)
+	seedPool.put(nearest)
+	return output
+}
+
+// unreached is the column distance of pixels without a seed in their column.
+const unreached = -1
+
+// distanceTransform returns the nearest seed of every pixel, or noSeed.
+func distanceTransform(width, height int, opaqueMask []bool, opts Options) []int32 {
+	nearest := seedPool.get(width * height)
+	columnDistances := distancePool.get(width * height)
+
+	// Nearest seed within each column.
+	forBands(width, opts, func(start, end int) {
+		for x := start; x < end; x++ {
+			transformColumn(x, width, height, opaqueMask, columnDistances, nearest, opts.Edge)
+		}
+	})
+	opts.progress(0.5)
+
+	// Nearest of the column seeds along each row.
+	forBands(height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			transformRow(y, width, columnDistances, nearest, opts)
+		}
+	})
+	opts.progress(1)
+	distancePool.put(columnDistances)
+
+	return nearest
+}
+
+// transformColumn finds the nearest seed in column x for every pixel of the
+// column, storing its vertical distance and position.
+func transformColumn(x, width, height int, opaqueMask []bool, distances []int32, nearest []int32, edge Edge) {
+	// With wrapping, seeds of the previous and next period count as well.
+	from, to := 0, height
+	if edge == EdgeWrap {
+		from, to = -height, 2*height
+	}
+	seedAt := func(y int) bool {
+		return opaqueMask[((y%height+height)%height)*width+x]
+	}
+
+	above := make([]int, height)
+	last, found := 0, false
+	for y := from; y < min(to, height); y++ {
+		if seedAt(y) {
+			last, found = y, true
+		}
+		if y >= 0 {
+			above[y] = unreached
+			if found {
+				above[y] = y - last
+			}
+		}
+	}
+
+	next, found := 0, false
+	for y := to - 1; y >= 0; y-- {
+		if seedAt(y) {
+			next, found = y, true
+		}
+		if y >= height {
+			continue
+		}
+
+		idx := y*width + x
+		d := above[y]
+		row := y - d
+		if found && (d == unreached || next-y < d) {
+			d, row = next-y, next
+		}
+		distances[idx] = int32(d)
+		if d == unreached {
+			nearest[idx] = noSeed
+		} else {
+			nearest[idx] = int32(((row%height+height)%height)*width + x)
+		}
+	}
+}
+
+// transformRow computes the lower envelope of the distance functions rooted
+// at the column seeds of row y, and replaces the nearest seeds of the row
+// with the seeds whose function is lowest at each pixel.
+func transformRow(y, width int, distances []int32, nearest []int32, opts Options) {
+	row := distances[y*width : (y+1)*width]
+	columns := nearest[y*width : (y+1)*width]
+	metric := opts.Metric
+
+	// Sites are unwrapped x coordinates, so that with wrapping the columns
+	// of the previous and next period count as well.
+	from, to := 0, width
+	if opts.Edge == EdgeWrap {
+		from, to = -width, 2*width
+	}
+	g := func(x int) int64 {
+		return int64(row[(x%width+width)%width])
+	}
+
+	// The envelope: sites and the first x from which each is the nearest.
+	var sites []int
+	var starts []int64
+	for u := from; u < to; u++ {
+		gu := g(u)
+		if gu == unreached {
+			continue
+		}
+		for len(sites) > 0 {
+			q := len(sites) - 1
+			t := int(starts[q])
+			if metric.rowDistance(t, sites[q], g(sites[q])) <= metric.rowDistance(t, u, gu) {
+				break
+			}
+			sites, starts = sites[:q], starts[:q]
+		}
+		if len(sites) == 0 {
+			sites, starts = append(sites, u), append(starts, 0)
+			continue
+		}
+		q := len(sites) - 1
+		if start := 1 + metric.separation(sites[q], u, g(sites[q]), gu); start < int64(width) {
+			sites, starts = append(sites, u), append(starts, start)
+		}
+	}
+	if len(sites) == 0 {
+		return
+	}
+
+	seeds := make([]int32, width)
+	k := 0
+	for x := 0; x < width; x++ {
+		for k+1 < len(sites) && starts[k+1] <= int64(x) {
+			k++
+		}
+		seeds[x] = columns[(sites[k]%width+width)%width]
+	}
+	copy(columns, seeds)
+}
+
+// forBands calls process for bands of the range [0, n), in parallel, or on the
+// calling goroutine in small bands with checkpoints in between when run by a
+// Job.
+func forBands(n int, opts Options, process func(start, end int)) {
+	if opts.checkpoint != nil {
+		for start := 0; start < n; start += cooperativeRows {
+			process(start, min(start+cooperativeRows, n))
+			opts.yield()
+		}
+		return
+	}
+
+	threads := opts.threads()
+	chunkSize := max(1, (n+threads-1)/threads)
+
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunkSize {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			process(start, end)
+		}(start, min(start+chunkSize, n))
+	}
+	wg.Wait()
+}
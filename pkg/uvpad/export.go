@@ -0,0 +1,186 @@
+package uvpad
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// WriteRaw writes the distance of every pixel to its nearest seed as
+// Width*Height little endian float32 values, row by row, +Inf for unreached
+// pixels. With nearest, Width*Height int32 indices of the nearest seeds
+// follow, -1 for unreached pixels.
+func (f *Field) WriteRaw(w io.Writer, nearest bool) error {
+	bw := bufio.NewWriter(w)
+	var b [4]byte
+	for idx := range f.Nearest {
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(f.Distance(idx))))
+		bw.Write(b[:])
+	}
+	if nearest {
+		for _, seed := range f.Nearest {
+			binary.LittleEndian.PutUint32(b[:], uint32(int32(seed)))
+			bw.Write(b[:])
+		}
+	}
+	return bw.Flush()
+}
+
+// The pixel types of OpenEXR channels.
+const (
+	exrUint  = 0
+	exrFloat = 2
+)
+
+// WriteEXR writes the distance of every pixel to its nearest seed as the Y
+// channel of an uncompressed OpenEXR image, +Inf for unreached pixels. With
+// nearest, a UINT channel named nearest holds the indices of the nearest
+// seeds, 0xffffffff for unreached pixels.
+func (f *Field) WriteEXR(w io.Writer, nearest bool) error {
+	channels := []exrChannel{{"Y", exrFloat, func(idx int) uint32 {
+		return math.Float32bits(float32(f.Distance(idx)))
+	}}}
+	if nearest {
+		channels = append(channels, exrChannel{"nearest", exrUint, func(idx int) uint32 {
+			return uint32(int32(f.Nearest[idx]))
+		}})
+	}
+	return writeEXR(w, f.Width, f.Height, channels)
+}
+
+// FloatImage is an image of float samples, such as a float signed distance
+// field, that the exr format writes without clamping. Other formats see it
+// clamped to 16 bits.
+type FloatImage struct {
+	*Buffer[float32]
+}
+
+// FloatModel is the color model of FloatImage, which tells its 4 byte samples
+// apart from 16-bit ones. It converts colors like color.NRGBA64Model.
+var FloatModel = color.ModelFunc(color.NRGBA64Model.Convert)
+
+func (f FloatImage) ColorModel() color.Model { return FloatModel }
+
+func (f FloatImage) Bounds() image.Rectangle { return image.Rect(0, 0, f.Width, f.Height) }
+
+func (f FloatImage) At(x, y int) color.Color {
+	pixel := f.Pix[(y*f.Width+x)*4:][:4]
+	return color.NRGBA64{
+		denormalize[uint16](float64(pixel[0])),
+		denormalize[uint16](float64(pixel[1])),
+		denormalize[uint16](float64(pixel[2])),
+		denormalize[uint16](float64(pixel[3])),
+	}
+}
+
+// encodeEXR writes img as an uncompressed OpenEXR image with float RGBA
+// channels, the samples of a FloatImage as they are.
+func encodeEXR(w io.Writer, img image.Image) error {
+	var b *Buffer[float32]
+	if f, ok := img.(FloatImage); ok {
+		b = f.Buffer
+	} else {
+		switch buf := FromImage(img).(type) {
+		case *Buffer[uint8]:
+			b = Convert[float32](buf)
+		case *Buffer[uint16]:
+			b = Convert[float32](buf)
+		}
+	}
+
+	channel := func(name string, c int) exrChannel {
+		return exrChannel{name, exrFloat, func(idx int) uint32 {
+			return math.Float32bits(b.Pix[idx*4+c])
+		}}
+	}
+	return writeEXR(w, b.Width, b.Height, []exrChannel{
+		channel("A", 3), channel("B", 2), channel("G", 1), channel("R", 0),
+	})
+}
+
+// exrChannel is a channel of an OpenEXR image and the bits of its value at
+// every pixel.
+type exrChannel struct {
+	name      string
+	pixelType uint32
+	value     func(idx int) uint32
+}
+
+// writeEXR writes an uncompressed single part scanline OpenEXR image. The
+// channels must be sorted by name.
+func writeEXR(w io.Writer, width, height int, channels []exrChannel) error {
+	var header []byte
+	u32 := func(v uint32) { header = binary.LittleEndian.AppendUint32(header, v) }
+	attribute := func(name, typ string, size int) {
+		header = append(header, name...)
+		header = append(header, 0)
+		header = append(header, typ...)
+		header = append(header, 0)
+		u32(uint32(size))
+	}
+
+	u32(20000630) // magic number
+	u32(2)        // version 2, single part scanline image
+
+	chlist := 1
+	for _, c := range channels {
+		chlist += len(c.name) + 1 + 16
+	}
+	attribute("channels", "chlist", chlist)
+	for _, c := range channels {
+		header = append(header, c.name...)
+		header = append(header, 0)
+		u32(c.pixelType)
+		u32(0) // pLinear and reserved
+		u32(1) // x sampling
+		u32(1) // y sampling
+	}
+	header = append(header, 0)
+
+	attribute("compression", "compression", 1)
+	header = append(header, 0) // NO_COMPRESSION
+	for _, window := range []string{"dataWindow", "displayWindow"} {
+		attribute(window, "box2i", 16)
+		u32(0)
+		u32(0)
+		u32(uint32(width - 1))
+		u32(uint32(height - 1))
+	}
+	attribute("lineOrder", "lineOrder", 1)
+	header = append(header, 0) // INCREASING_Y
+	attribute("pixelAspectRatio", "float", 4)
+	u32(math.Float32bits(1))
+	attribute("screenWindowCenter", "v2f", 8)
+	u32(0)
+	u32(0)
+	attribute("screenWindowWidth", "float", 4)
+	u32(math.Float32bits(1))
+	header = append(header, 0)
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+
+	// The offset table points at every scanline, each a y coordinate, a
+	// size and the channels of the line one after the other.
+	lineSize := 4 * width * len(channels)
+	var b [8]byte
+	for y := range height {
+		binary.LittleEndian.PutUint64(b[:], uint64(len(header)+8*height+y*(8+lineSize)))
+		bw.Write(b[:])
+	}
+	for y := range height {
+		binary.LittleEndian.PutUint32(b[:4], uint32(y))
+		binary.LittleEndian.PutUint32(b[4:], uint32(lineSize))
+		bw.Write(b[:])
+		for _, c := range channels {
+			for x := range width {
+				binary.LittleEndian.PutUint32(b[:4], c.value(y*width+x))
+				bw.Write(b[:4])
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,116 @@
+package uvpad
+
+import (
+	"fmt"
+	"math"
+)
+
+// Falloff is the curve along which dilated colors fade toward
+// Options.FadeColor.
+type Falloff int
+
+const (
+	// FalloffGaussian fades along a gaussian whose standard deviation is a
+	// third of the fade distance, so the color is nearly gone at the fade
+	// distance.
+	FalloffGaussian Falloff = iota
+	// FalloffLinear fades linearly, reaching the fade color at the fade
+	// distance.
+	FalloffLinear
+)
+
+var falloffNames = []string{"gaussian", "linear"}
+
+func (f Falloff) String() string {
+	if int(f) < len(falloffNames) {
+		return falloffNames[f]
+	}
+	return fmt.Sprintf("Falloff(%d)", int(f))
+}
+
+// ParseFalloff returns the falloff with the given name: gaussian or linear.
+func ParseFalloff(name string) (Falloff, error) {
+	for i, n := range falloffNames {
+		if n == name {
+			return Falloff(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown falloff %q, expected gaussian or linear", name)
+}
+
+// weight returns how much of the dilated color remains at distance d.
+func (f Falloff) weight(d, fadeDistance float64) float64 {
+	t := d / fadeDistance
+	if f == FalloffLinear {
+		return max(0, 1-t)
+	}
+	return math.Exp(-4.5 * t * t)
+}
+
+// fade blends the dilated pixels of dst toward opts.FadeColor by their
+// distance from the seeds of src.
+func fade[T Sample](dst, src *Buffer[T], opts Options) {
+	for idx, d := range seedDistances(src, opts) {
+		pixel := dst.Pix[idx*4 : idx*4+4]
+		if d == 0 || math.IsInf(d, 1) || pixel[3] == 0 {
+			continue
+		}
+
+		w := opts.Fade.weight(d, opts.FadeDistance)
+		for c := range pixel {
+			pixel[c] = denormalize[T](w*normalize(pixel[c]) + (1-w)*opts.FadeColor[c])
+		}
+	}
+}
+
+// feather ramps the alpha of the dilated pixels of dst down to 0 over the
+// last opts.Feather pixels before the radius, or over the first
+// opts.Feather pixels from the seeds of src without a radius.
+func feather[T Sample](dst, src *Buffer[T], opts Options) {
+	end := opts.Radius
+	if end <= 0 {
+		end = opts.Feather
+	}
+	for idx, d := range seedDistances(src, opts) {
+		pixel := dst.Pix[idx*4 : idx*4+4]
+		if d == 0 || pixel[3] == 0 {
+			continue
+		}
+		ramp := max(0, min(1, (end-d)/opts.Feather))
+		pixel[3] = denormalize[T](ramp * normalize(pixel[3]))
+	}
+}
+
+// seedDistances returns the distance of every pixel from the nearest seed of
+// src, 0 for the seeds and +Inf if there are none.
+func seedDistances[T Sample](src *Buffer[T], opts Options) []float64 {
+	width, height := src.Width, src.Height
+
+	// The algorithm has already reported its progress.
+	transformOpts := opts
+	transformOpts.Progress = nil
+	mask := seedMask(src, opts)
+	nearest := distanceTransform(width, height, mask, transformOpts)
+	distances := nearestDistances(width, height, nearest, opts)
+	maskPool.put(mask)
+	seedPool.put(nearest)
+	return distances
+}
+
+// nearestDistances returns the distance of every pixel from its nearest seed,
+// +Inf if it has none.
+func nearestDistances(width, height int, nearest []int32, opts Options) []float64 {
+	distances := make([]float64, len(nearest))
+	for idx, seed := range nearest {
+		if seed == noSeed {
+			distances[idx] = math.Inf(1)
+			continue
+		}
+		d := float64(opts.Metric.distance(seedOffset(idx%width, idx/width, seed, width, height, opts.Edge)))
+		if opts.Metric == MetricEuclidean {
+			d = math.Sqrt(d)
+		}
+		distances[idx] = d
+	}
+	return distances
+}
@@ -0,0 +1,450 @@
+package uvpad
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+const fbxMagic = "Kaydara FBX Binary  \x00"
+
+// fbxNode is a node record of a binary FBX file. Its properties are int64,
+// float64, bool, string, []byte, []int or []float64 values.
+type fbxNode struct {
+	name       string
+	properties []any
+	children   []*fbxNode
+}
+
+func (n *fbxNode) child(name string) *fbxNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// property returns the property at i of the first child with the given
+// name, nil if there is none.
+func (n *fbxNode) property(name string, i int) any {
+	if c := n.child(name); c != nil && i < len(c.properties) {
+		return c.properties[i]
+	}
+	return nil
+}
+
+// ints returns the integer array held by the first child with the given
+// name.
+func (n *fbxNode) ints(name string) []int {
+	v, _ := n.property(name, 0).([]int)
+	return v
+}
+
+// objectName returns the name of an object node, stored before its class
+// as "name\x00\x01class".
+func (n *fbxNode) objectName() string {
+	if len(n.properties) < 2 {
+		return ""
+	}
+	name, _ := n.properties[1].(string)
+	name, _, _ = strings.Cut(name, "\x00\x01")
+	return name
+}
+
+func (n *fbxNode) id() int64 {
+	if len(n.properties) == 0 {
+		return 0
+	}
+	id, _ := n.properties[0].(int64)
+	return id
+}
+
+// ParseFBX reads the UV layout of the meshes of a binary FBX model that
+// match filter, from the UV layer of every geometry selected by
+// filter.UVSet, the first by default. Meshes are named
+// after their models and materials after the materials connected to them,
+// in slot order.
+func ParseFBX(r io.Reader, filter MeshFilter) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(fbxMagic)) {
+		if bytes.Contains(data[:min(len(data), 1024)], []byte("FBXHeaderExtension")) {
+			return nil, errors.New("fbx: only the binary format is supported")
+		}
+		return nil, errors.New("fbx: not an FBX file")
+	}
+	if len(data) < 27 {
+		return nil, errors.New("fbx: truncated header")
+	}
+
+	p := fbxParser{data: data, offset: 27, wide: binary.LittleEndian.Uint32(data[23:]) >= 7500}
+	root := &fbxNode{}
+	for p.offset < len(p.data) {
+		node, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		if node == nil {
+			break
+		}
+		root.children = append(root.children, node)
+	}
+
+	objects := root.child("Objects")
+	if objects == nil {
+		return nil, errors.New("fbx: missing objects")
+	}
+
+	// Connections link geometries and materials to their models, the
+	// materials in the order of their slots.
+	models := map[int64]*fbxNode{}
+	materials := map[int64]*fbxNode{}
+	for _, c := range objects.children {
+		switch c.name {
+		case "Model":
+			models[c.id()] = c
+		case "Material":
+			materials[c.id()] = c
+		}
+	}
+	geometryModel := map[int64]*fbxNode{}
+	modelMaterials := map[int64][]*fbxNode{}
+	if connections := root.child("Connections"); connections != nil {
+		for _, c := range connections.children {
+			if c.name != "C" || len(c.properties) < 3 {
+				continue
+			}
+			child, _ := c.properties[1].(int64)
+			parent, _ := c.properties[2].(int64)
+			if model, ok := models[parent]; ok {
+				if material, ok := materials[child]; ok {
+					modelMaterials[parent] = append(modelMaterials[parent], material)
+				} else {
+					geometryModel[child] = model
+				}
+			}
+		}
+	}
+
+	m := &Mesh{}
+	geometryIdx := -1
+	for _, geometry := range objects.children {
+		if geometry.name != "Geometry" {
+			continue
+		}
+		geometryIdx++
+
+		name := geometry.objectName()
+		var slots []*fbxNode
+		if model, ok := geometryModel[geometry.id()]; ok {
+			name = model.objectName()
+			slots = modelMaterials[model.id()]
+		}
+		if !selects(filter.Mesh, name, geometryIdx) {
+			continue
+		}
+		// Control points of every geometry are distinct 3D vertices.
+		base := len(m.Vertices)
+		coordinates, _ := geometry.property("Vertices", 0).([]float64)
+		for i := 0; i+2 < len(coordinates); i += 3 {
+			m.Vertices = append(m.Vertices, [3]float64{coordinates[i], coordinates[i+1], coordinates[i+2]})
+		}
+		if err := m.addFBXGeometry(geometry, slots, base, filter); err != nil {
+			return nil, fmt.Errorf("fbx: geometry %s: %w", name, err)
+		}
+	}
+	return m, nil
+}
+
+// addFBXGeometry adds the polygons of geometry whose materials, given by
+// their slots, match filter. Its control points are numbered from base.
+func (m *Mesh) addFBXGeometry(geometry *fbxNode, slots []*fbxNode, base int, filter MeshFilter) error {
+	// UV layers are named, and numbered by their first property.
+	var layer *fbxNode
+	for _, c := range geometry.children {
+		if c.name != "LayerElementUV" {
+			continue
+		}
+		name, _ := c.property("Name", 0).(string)
+		switch {
+		case filter.UVSet != "":
+			if layer == nil && selects(filter.UVSet, name, int(c.id())) {
+				layer = c
+			}
+		case layer == nil || c.id() == 0:
+			layer = c
+		}
+	}
+	if layer == nil {
+		return nil
+	}
+
+	uvs, _ := layer.property("UV", 0).([]float64)
+	mapping, _ := layer.property("MappingInformationType", 0).(string)
+	reference, _ := layer.property("ReferenceInformationType", 0).(string)
+	uvIndex := layer.ints("UVIndex")
+	polygonVertices := geometry.ints("PolygonVertexIndex")
+
+	var polygonMaterials []int
+	allSame := true
+	if material := geometry.child("LayerElementMaterial"); material != nil {
+		polygonMaterials = material.ints("Materials")
+		mapping, _ := material.property("MappingInformationType", 0).(string)
+		allSame = mapping != "ByPolygon"
+	}
+	materialSelected := func(polygon int) bool {
+		if filter.Material == "" {
+			return true
+		}
+		slot := 0
+		if !allSame && polygon < len(polygonMaterials) {
+			slot = polygonMaterials[polygon]
+		} else if len(polygonMaterials) > 0 {
+			slot = polygonMaterials[0]
+		}
+		name := ""
+		if slot >= 0 && slot < len(slots) {
+			name = slots[slot].objectName()
+		}
+		return selects(filter.Material, name, slot)
+	}
+
+	uvBase := len(m.UV)
+	for i := 0; i+1 < len(uvs); i += 2 {
+		m.UV = append(m.UV, [2]float64{uvs[i], uvs[i+1]})
+	}
+	count := len(m.UV) - uvBase
+
+	// uv returns the texture coordinate of the polygon vertex k at control
+	// point vertex.
+	uv := func(k, vertex int) (int, error) {
+		idx := k
+		if mapping == "ByVertice" || mapping == "ByVertex" || mapping == "ByControlPoint" {
+			idx = vertex
+		}
+		if reference == "IndexToDirect" || reference == "Index" {
+			if idx >= len(uvIndex) {
+				return 0, errors.New("UV index out of range")
+			}
+			idx = uvIndex[idx]
+		}
+		if idx < 0 || idx >= count {
+			return 0, errors.New("UV out of range")
+		}
+		return uvBase + idx, nil
+	}
+
+	var polygon, positions []int
+	polygonIdx := 0
+	for k, v := range polygonVertices {
+		// The last vertex of every polygon is stored complemented.
+		last := v < 0
+		if last {
+			v = ^v
+		}
+		idx, err := uv(k, v)
+		if err != nil {
+			return err
+		}
+		polygon = append(polygon, idx)
+		positions = append(positions, base+v)
+		if !last {
+			continue
+		}
+		if materialSelected(polygonIdx) {
+			for i := 2; i < len(polygon); i++ {
+				m.Triangles = append(m.Triangles, [3]int{polygon[0], polygon[i-1], polygon[i]})
+				m.Positions = append(m.Positions, [3]int{positions[0], positions[i-1], positions[i]})
+			}
+		}
+		polygon, positions = polygon[:0], positions[:0]
+		polygonIdx++
+	}
+	return nil
+}
+
+// fbxParser reads the node records of a binary FBX file, whose offsets and
+// counts are 64-bit from version 7.5.
+type fbxParser struct {
+	data   []byte
+	offset int
+	wide   bool
+}
+
+var errFBXTruncated = errors.New("fbx: truncated file")
+
+func (p *fbxParser) read(n int) ([]byte, error) {
+	if n < 0 || p.offset+n > len(p.data) {
+		return nil, errFBXTruncated
+	}
+	b := p.data[p.offset : p.offset+n]
+	p.offset += n
+	return b, nil
+}
+
+func (p *fbxParser) uint() (int, error) {
+	if p.wide {
+		b, err := p.read(8)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.LittleEndian.Uint64(b)), nil
+	}
+	b, err := p.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(b)), nil
+}
+
+// node reads a node record and its children, nil for the null record that
+// ends a list of nodes.
+func (p *fbxParser) node() (*fbxNode, error) {
+	end, err := p.uint()
+	if err != nil {
+		return nil, err
+	}
+	count, err := p.uint()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.uint(); err != nil { // property list length
+		return nil, err
+	}
+	nameLen, err := p.read(1)
+	if err != nil {
+		return nil, err
+	}
+	if end == 0 {
+		return nil, nil
+	}
+	if end > len(p.data) || end < p.offset {
+		return nil, errFBXTruncated
+	}
+	name, err := p.read(int(nameLen[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	n := &fbxNode{name: string(name)}
+	for range count {
+		v, err := p.property()
+		if err != nil {
+			return nil, fmt.Errorf("fbx: node %s: %w", n.name, err)
+		}
+		n.properties = append(n.properties, v)
+	}
+	for p.offset < end {
+		child, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			break
+		}
+		n.children = append(n.children, child)
+	}
+	p.offset = end
+	return n, nil
+}
+
+func (p *fbxParser) property() (any, error) {
+	typ, err := p.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	scalar := map[byte]int{'Y': 2, 'C': 1, 'I': 4, 'F': 4, 'D': 8, 'L': 8}
+	if size, ok := scalar[typ[0]]; ok {
+		b, err := p.read(size)
+		if err != nil {
+			return nil, err
+		}
+		return fbxValue(typ[0], b), nil
+	}
+
+	switch typ[0] {
+	case 'S', 'R':
+		b, err := p.read(4)
+		if err != nil {
+			return nil, err
+		}
+		b, err = p.read(int(binary.LittleEndian.Uint32(b)))
+		if err != nil {
+			return nil, err
+		}
+		if typ[0] == 'S' {
+			return string(b), nil
+		}
+		return b, nil
+	case 'f', 'd', 'l', 'i', 'b':
+		header, err := p.read(12)
+		if err != nil {
+			return nil, err
+		}
+		length := int(binary.LittleEndian.Uint32(header))
+		encoding := binary.LittleEndian.Uint32(header[4:])
+		b, err := p.read(int(binary.LittleEndian.Uint32(header[8:])))
+		if err != nil {
+			return nil, err
+		}
+		if encoding == 1 {
+			zr, err := zlib.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			if b, err = io.ReadAll(zr); err != nil {
+				return nil, err
+			}
+		}
+
+		elem := map[byte]byte{'f': 'F', 'd': 'D', 'l': 'L', 'i': 'I', 'b': 'C'}[typ[0]]
+		size := scalar[elem]
+		if len(b) < length*size {
+			return nil, errFBXTruncated
+		}
+		if slices.Contains([]byte("fd"), typ[0]) {
+			values := make([]float64, length)
+			for i := range values {
+				values[i] = fbxValue(elem, b[i*size:]).(float64)
+			}
+			return values, nil
+		}
+		if typ[0] == 'b' {
+			return b[:length], nil
+		}
+		values := make([]int, length)
+		for i := range values {
+			values[i] = int(fbxValue(elem, b[i*size:]).(int64))
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unknown property type %q", typ[0])
+}
+
+// fbxValue decodes a scalar property of the given type.
+func fbxValue(typ byte, b []byte) any {
+	switch typ {
+	case 'Y':
+		return int64(int16(binary.LittleEndian.Uint16(b)))
+	case 'C':
+		return b[0] != 0
+	case 'I':
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	case 'F':
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case 'D':
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	default: // 'L'
+		return int64(binary.LittleEndian.Uint64(b))
+	}
+}
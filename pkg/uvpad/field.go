@@ -0,0 +1,168 @@
+package uvpad
+
+import "math"
+
+// nearestAlgorithm is an algorithm that fills every pixel from its nearest
+// seed, as found by transform.
+type nearestAlgorithm struct {
+	generic
+	transform func(width, height int, opaqueMask []bool, opts Options) []int32
+}
+
+// Field is the nearest seed of every pixel of an image.
+type Field struct {
+	Width, Height int
+
+	// Nearest is the index y*Width+x of the nearest seed of every pixel, -1
+	// for the pixels the dilation doesn't reach.
+	Nearest []int
+
+	// edge and metric measure the offsets and distances to the seeds.
+	edge   Edge
+	metric Metric
+}
+
+// NearestSeeds returns the nearest seed of every pixel of src as alg finds
+// it, so the jump flood of jfa shows the seeds it picks wrongly. Algorithms
+// that don't fill from the nearest seed use the exact distance transform.
+func NearestSeeds(alg Algorithm, src Image, opts Options) *Field {
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return nearestSeeds(alg, src, opts)
+	case *Buffer[uint16]:
+		return nearestSeeds(alg, src, opts)
+	case *Buffer[float32]:
+		return nearestSeeds(alg, src, opts)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+func nearestSeeds[T Sample](alg Algorithm, src *Buffer[T], opts Options) *Field {
+	if opts.changesCoverage() {
+		src, opts = applyCoverage(src, opts)
+	}
+	width, height := src.Width, src.Height
+
+	transform := distanceTransform
+	if n, ok := alg.(nearestAlgorithm); ok {
+		transform = n.transform
+	}
+	nearest := transform(width, height, seedMask(src, opts), opts)
+
+	f := &Field{Width: width, Height: height, Nearest: make([]int, len(nearest)), edge: opts.Edge, metric: opts.Metric}
+	limit := opts.Metric.limit(opts.Radius)
+	for idx, seed := range nearest {
+		f.Nearest[idx] = -1
+		if seed == noSeed {
+			continue
+		}
+		if dx, dy := seedOffset(idx%width, idx/width, seed, width, height, opts.Edge); opts.Metric.within(dx, dy, limit) {
+			f.Nearest[idx] = int(seed)
+		}
+	}
+	return f
+}
+
+// Voronoi returns an image that colors every pixel with a color hashed from
+// its nearest seed, which shows where every dilated pixel takes its color
+// from. Unreached pixels are transparent.
+func (f *Field) Voronoi() *Buffer[uint8] {
+	out := NewBuffer[uint8](f.Width, f.Height)
+	for idx, seed := range f.Nearest {
+		if seed == -1 {
+			continue
+		}
+		// A splitmix64 finalizer spreads neighbouring seeds apart.
+		h := uint64(seed) + 0x9e3779b97f4a7c15
+		h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+		h = (h ^ h>>27) * 0x94d049bb133111eb
+		h ^= h >> 31
+		pixel := out.Pix[idx*4 : idx*4+4]
+		pixel[0], pixel[1], pixel[2], pixel[3] = uint8(h), uint8(h>>8), uint8(h>>16), 0xff
+	}
+	return out
+}
+
+// Offset returns the offset from the pixel at idx to its nearest seed, the
+// shortest one across the border of tiling images, and whether it has one.
+func (f *Field) Offset(idx int) (dx, dy int, ok bool) {
+	seed := f.Nearest[idx]
+	if seed == -1 {
+		return 0, 0, false
+	}
+	return f.edge.offset(seed%f.Width-idx%f.Width, f.Width), f.edge.offset(seed/f.Width-idx/f.Width, f.Height), true
+}
+
+// OffsetMap returns an image whose red and green channels hold the offset
+// from every pixel to its nearest seed, biased by 32768, for dilating at
+// runtime in shaders. Unreached pixels are transparent.
+func (f *Field) OffsetMap() *Buffer[uint16] {
+	out := NewBuffer[uint16](f.Width, f.Height)
+	for idx := range f.Nearest {
+		dx, dy, ok := f.Offset(idx)
+		if !ok {
+			continue
+		}
+		pixel := out.Pix[idx*4 : idx*4+4]
+		pixel[0], pixel[1], pixel[3] = uint16(dx+32768), uint16(dy+32768), 0xffff
+	}
+	return out
+}
+
+// Distance returns the distance from the pixel at idx to its nearest seed,
+// +Inf if it has none.
+func (f *Field) Distance(idx int) float64 {
+	dx, dy, ok := f.Offset(idx)
+	if !ok {
+		return math.Inf(1)
+	}
+	d := float64(f.metric.distance(dx, dy))
+	if f.metric == MetricEuclidean {
+		d = math.Sqrt(d)
+	}
+	return d
+}
+
+// heatmapStops are the colors of the heatmap, evenly spaced from the seeds
+// to the far end, after the inferno color map.
+var heatmapStops = [][3]float64{
+	{0, 0, 4}, {87, 16, 110}, {188, 55, 84}, {249, 142, 9}, {252, 255, 164},
+}
+
+// Heatmap returns an image coloring every pixel by its distance to its
+// nearest seed, from black at the seeds to pale yellow at scale pixels, or at
+// the largest distance if scale is zero. Unreached pixels are transparent.
+func (f *Field) Heatmap(scale float64) *Buffer[uint8] {
+	distances := make([]float64, len(f.Nearest))
+	for idx := range distances {
+		distances[idx] = f.Distance(idx)
+	}
+	if scale <= 0 {
+		for _, d := range distances {
+			if !math.IsInf(d, 1) {
+				scale = max(scale, d)
+			}
+		}
+	}
+
+	out := NewBuffer[uint8](f.Width, f.Height)
+	last := len(heatmapStops) - 1
+	for idx, d := range distances {
+		if math.IsInf(d, 1) {
+			continue
+		}
+		t := 0.0
+		if scale > 0 {
+			t = min(1, d/scale) * float64(last)
+		}
+		i := min(int(t), last-1)
+		from, to := heatmapStops[i], heatmapStops[i+1]
+		pixel := out.Pix[idx*4 : idx*4+4]
+		for c := range 3 {
+			pixel[c] = uint8(math.Round(from[c] + (to[c]-from[c])*(t-float64(i))))
+		}
+		pixel[3] = 0xff
+	}
+	return out
+}
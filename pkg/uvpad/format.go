@@ -0,0 +1,105 @@
+package uvpad
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	formatsMu  sync.RWMutex
+	encoders   = map[string]func(io.Writer, image.Image) error{}
+	extensions = map[string]string{}
+)
+
+func init() {
+	registerEncoder("png", png.Encode, ".png")
+	registerEncoder("jpeg", func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 95})
+	}, ".jpg", ".jpeg")
+	registerEncoder("exr", encodeEXR, ".exr")
+}
+
+// RegisterFormat registers an image container so that Decode recognizes it by
+// its magic prefix (with image.RegisterFormat's "?" wildcards) and Encode can
+// write it. Files with the extension "."+name are written with encode. Either
+// function may be nil for read-only or write-only formats.
+func RegisterFormat(name, magic string, decode func(io.Reader) (image.Image, error), encode func(io.Writer, image.Image) error) {
+	if decode != nil {
+		image.RegisterFormat(name, magic, decode, func(r io.Reader) (image.Config, error) {
+			img, err := decode(r)
+			if err != nil {
+				return image.Config{}, err
+			}
+			return image.Config{
+				ColorModel: img.ColorModel(),
+				Width:      img.Bounds().Dx(),
+				Height:     img.Bounds().Dy(),
+			}, nil
+		})
+	}
+	if encode != nil {
+		registerEncoder(name, encode, "."+name)
+	}
+}
+
+func registerEncoder(name string, encode func(io.Writer, image.Image) error, exts ...string) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	encoders[name] = encode
+	for _, ext := range exts {
+		extensions[ext] = name
+	}
+}
+
+// Decode decodes an image in any registered format, returning the format name.
+func Decode(r io.Reader) (image.Image, string, error) {
+	return image.Decode(r)
+}
+
+// DecodeConfig decodes the dimensions and color model of an image in any
+// registered format without decoding the pixels.
+func DecodeConfig(r io.Reader) (image.Config, string, error) {
+	return image.DecodeConfig(r)
+}
+
+// Encode writes img in the named format.
+func Encode(w io.Writer, img image.Image, format string) error {
+	formatsMu.RLock()
+	encode, ok := encoders[format]
+	formatsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("no encoder registered for format %q", format)
+	}
+	return encode(w, img)
+}
+
+// Formats returns the sorted names of all formats Encode can write.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatForPath returns the name of the format written for a file name, based
+// on its extension, or "" if no registered format claims the extension.
+func FormatForPath(path string) string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
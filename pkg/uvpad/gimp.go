@@ -0,0 +1,119 @@
+package uvpad
+
+import "sync/atomic"
+
+func init() {
+	Register("gimp", generic{
+		processGIMP[uint8], processGIMP[uint16], processGIMP[float32],
+		func(bufferBytes int64) int64 {
+			// The buffer every other pass writes to.
+			return bufferBytes
+		},
+	})
+}
+
+// processGIMP is the GIMP UVPad dilation: transparent pixels are repeatedly
+// filled with the average of their opaque 4-neighbours, one ring per pass. A
+// radius limits the number of passes. Options.Connectivity and
+// Options.Kernel widen the neighbourhood, so fewer passes are needed.
+func processGIMP[T Sample](input *Buffer[T], opts Options) *Buffer[T] {
+	width, height := input.Width, input.Height
+	opaque := opaqueValue[T]()
+	seed := seedAlpha[T](opts)
+
+	output := input.Clone()
+
+	remaining := 0
+	for idx := 0; idx < width*height; idx++ {
+		if output.Pix[idx*4+3] < seed {
+			remaining++
+		}
+	}
+
+	kernel := max(1, opts.Kernel)
+	neighbours := gimpNeighbours(opts.Connectivity, kernel)
+
+	// Every pass grows the filled area by the kernel radius.
+	maxPasses := (int(opts.Radius) + kernel - 1) / kernel
+
+	// Every pass reads the pixels of the previous one and writes its own to
+	// a copy, so the bands of rows run in parallel and two buffers are
+	// swapped rather than cloned.
+	next := NewBuffer[T](width, height)
+	total := width * height
+	passes := 0
+	for remaining > 0 && (opts.Radius <= 0 || passes < maxPasses) {
+		opts.logf("Pass %d: %d remaining\n", passes, remaining)
+		passes++
+
+		copy(next.Pix, output.Pix)
+		var filled atomic.Int64
+		forBands(height, opts, func(start, end int) {
+			bandFilled := 0
+			for y := start; y < end; y++ {
+				for x := 0; x < width; x++ {
+					pixelIdx := (y*width + x) * 4
+					if output.Pix[pixelIdx+3] >= seed {
+						continue
+					}
+
+					var r, g, b float64
+					var count float64
+
+					for _, n := range neighbours {
+						nx, okx := opts.Edge.resolve(x+n.dx, width)
+						ny, oky := opts.Edge.resolve(y+n.dy, height)
+						if okx && oky {
+							neighbourIdx := (ny*width + nx) * 4
+							if output.Pix[neighbourIdx+3] >= seed {
+								r += float64(output.Pix[neighbourIdx])
+								g += float64(output.Pix[neighbourIdx+1])
+								b += float64(output.Pix[neighbourIdx+2])
+								count++
+							}
+						}
+					}
+
+					if count > 0 {
+						next.Pix[pixelIdx] = T(r / count)
+						next.Pix[pixelIdx+1] = T(g / count)
+						next.Pix[pixelIdx+2] = T(b / count)
+						next.Pix[pixelIdx+3] = opaque
+						bandFilled++
+					}
+				}
+			}
+			filled.Add(int64(bandFilled))
+		})
+
+		output, next = next, output
+		if filled.Load() == 0 {
+			// Nothing to grow from: the image has no opaque pixels.
+			break
+		}
+		remaining -= int(filled.Load())
+		opts.progress(float64(total-remaining) / float64(total))
+	}
+	next.release()
+	opts.progress(1)
+
+	return output
+}
+
+// gimpNeighbours returns the offsets of the neighbours within kernel pixels,
+// measured along the axes (4-connectivity) or diagonally too (8).
+func gimpNeighbours(connectivity, kernel int) []struct{ dx, dy int } {
+	var neighbours []struct{ dx, dy int }
+	for dy := -kernel; dy <= kernel; dy++ {
+		for dx := -kernel; dx <= kernel; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			if connectivity != 8 && abs(dx)+abs(dy) > kernel {
+				continue
+			}
+			neighbours = append(neighbours, struct{ dx, dy int }{dx, dy})
+		}
+	}
+	return neighbours
+}
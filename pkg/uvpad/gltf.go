@@ -0,0 +1,338 @@
+package uvpad
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The glTF component types of accessors.
+const (
+	gltfUnsignedByte  = 5121
+	gltfUnsignedShort = 5123
+	gltfUnsignedInt   = 5125
+	gltfFloat         = 5126
+)
+
+// The glTF primitive modes that have triangles.
+const (
+	gltfTriangles     = 4
+	gltfTriangleStrip = 5
+	gltfTriangleFan   = 6
+)
+
+type gltfDocument struct {
+	Meshes []struct {
+		Name       string
+		Primitives []struct {
+			Attributes map[string]int
+			Indices    *int
+			Material   *int
+			Mode       *int
+		}
+	}
+	Materials []struct {
+		Name string
+	}
+	Accessors []struct {
+		BufferView    *int
+		ByteOffset    int
+		ComponentType int
+		Normalized    bool
+		Count         int
+		Type          string
+		Sparse        json.RawMessage
+	}
+	BufferViews []struct {
+		Buffer     int
+		ByteOffset int
+		ByteLength int
+		ByteStride int
+	}
+	Buffers []struct {
+		URI        string
+		ByteLength int
+	}
+}
+
+// ParseGLTF reads the UV layout of the primitives of a glTF 2.0 model that
+// match filter, from either a .gltf JSON document or a binary .glb file.
+// External buffers are read with load, given their URI; embedded data URIs
+// and the binary chunk of .glb files are read directly. The texture
+// coordinates are those of the TEXCOORD_n attribute of filter.UVSet,
+// TEXCOORD_0 by default, with v flipped up like the other model formats.
+func ParseGLTF(r io.Reader, load func(uri string) ([]byte, error), filter MeshFilter) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc gltfDocument
+	var bin []byte
+	if bytes.HasPrefix(data, []byte("glTF")) {
+		jsonChunk, binChunk, err := splitGLB(data)
+		if err != nil {
+			return nil, err
+		}
+		data, bin = jsonChunk, binChunk
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("gltf: %w", err)
+	}
+
+	buffers := make([][]byte, len(doc.Buffers))
+	buffer := func(i int) ([]byte, error) {
+		if i < 0 || i >= len(doc.Buffers) {
+			return nil, fmt.Errorf("gltf: buffer %d out of range", i)
+		}
+		if buffers[i] != nil {
+			return buffers[i], nil
+		}
+
+		var err error
+		switch uri := doc.Buffers[i].URI; {
+		case uri == "":
+			if bin == nil {
+				return nil, fmt.Errorf("gltf: buffer %d has no data", i)
+			}
+			buffers[i] = bin
+		case strings.HasPrefix(uri, "data:"):
+			_, encoded, ok := strings.Cut(uri, ";base64,")
+			if !ok {
+				return nil, fmt.Errorf("gltf: buffer %d is not a base64 data URI", i)
+			}
+			buffers[i], err = base64.StdEncoding.DecodeString(encoded)
+		default:
+			if load == nil {
+				return nil, fmt.Errorf("gltf: can't load external buffer %s", uri)
+			}
+			if unescaped, err := url.PathUnescape(uri); err == nil {
+				uri = unescaped
+			}
+			buffers[i], err = load(uri)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gltf: buffer %d: %w", i, err)
+		}
+		return buffers[i], nil
+	}
+
+	// accessor returns the components of the elements of an accessor,
+	// normalized for the normalized integer types.
+	accessor := func(i int) ([][]float64, error) {
+		if i < 0 || i >= len(doc.Accessors) {
+			return nil, fmt.Errorf("gltf: accessor %d out of range", i)
+		}
+		a := doc.Accessors[i]
+		if a.Sparse != nil {
+			return nil, fmt.Errorf("gltf: sparse accessor %d is not supported", i)
+		}
+		components := map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4}[a.Type]
+		size := map[int]int{gltfUnsignedByte: 1, gltfUnsignedShort: 2, gltfUnsignedInt: 4, gltfFloat: 4}[a.ComponentType]
+		if components == 0 || size == 0 {
+			return nil, fmt.Errorf("gltf: accessor %d has unsupported type %s of component type %d", i, a.Type, a.ComponentType)
+		}
+
+		values := make([][]float64, a.Count)
+		if a.BufferView == nil {
+			// Accessors without a buffer view are all zeros.
+			for e := range values {
+				values[e] = make([]float64, components)
+			}
+			return values, nil
+		}
+		if *a.BufferView < 0 || *a.BufferView >= len(doc.BufferViews) {
+			return nil, fmt.Errorf("gltf: buffer view %d out of range", *a.BufferView)
+		}
+		view := doc.BufferViews[*a.BufferView]
+		b, err := buffer(view.Buffer)
+		if err != nil {
+			return nil, err
+		}
+		if view.ByteOffset+view.ByteLength > len(b) {
+			return nil, fmt.Errorf("gltf: buffer view %d out of range of its buffer", *a.BufferView)
+		}
+		b = b[view.ByteOffset : view.ByteOffset+view.ByteLength]
+
+		stride := view.ByteStride
+		if stride == 0 {
+			stride = components * size
+		}
+		if a.Count > 0 && a.ByteOffset+(a.Count-1)*stride+components*size > len(b) {
+			return nil, fmt.Errorf("gltf: accessor %d out of range of its buffer view", i)
+		}
+		for e := range values {
+			values[e] = make([]float64, components)
+			for c := range components {
+				p := b[a.ByteOffset+e*stride+c*size:]
+				var v float64
+				switch a.ComponentType {
+				case gltfUnsignedByte:
+					v = float64(p[0])
+					if a.Normalized {
+						v /= math.MaxUint8
+					}
+				case gltfUnsignedShort:
+					v = float64(binary.LittleEndian.Uint16(p))
+					if a.Normalized {
+						v /= math.MaxUint16
+					}
+				case gltfUnsignedInt:
+					v = float64(binary.LittleEndian.Uint32(p))
+				case gltfFloat:
+					v = float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+				}
+				values[e][c] = v
+			}
+		}
+		return values, nil
+	}
+
+	uvSet := "TEXCOORD_0"
+	if filter.UVSet != "" {
+		uvSet = filter.UVSet
+		if _, err := strconv.Atoi(uvSet); err == nil {
+			uvSet = "TEXCOORD_" + uvSet
+		}
+	}
+
+	m := &Mesh{}
+	welded := map[[3]float64]int{}
+	for meshIdx, mesh := range doc.Meshes {
+		if !selects(filter.Mesh, mesh.Name, meshIdx) {
+			continue
+		}
+		for primIdx, prim := range mesh.Primitives {
+			if !selects(filter.Primitive, "", primIdx) {
+				continue
+			}
+			if filter.Material != "" {
+				if prim.Material == nil || *prim.Material >= len(doc.Materials) {
+					continue
+				}
+				if !selects(filter.Material, doc.Materials[*prim.Material].Name, *prim.Material) {
+					continue
+				}
+			}
+			mode := gltfTriangles
+			if prim.Mode != nil {
+				mode = *prim.Mode
+			}
+			uvAccessor, ok := prim.Attributes[uvSet]
+			if !ok || mode < gltfTriangles || mode > gltfTriangleFan {
+				continue
+			}
+
+			uvs, err := accessor(uvAccessor)
+			if err != nil {
+				return nil, err
+			}
+			var indices []int
+			if prim.Indices != nil {
+				values, err := accessor(*prim.Indices)
+				if err != nil {
+					return nil, err
+				}
+				for _, v := range values {
+					if int(v[0]) >= len(uvs) {
+						return nil, fmt.Errorf("gltf: index %d out of range of %d texture coordinates", int(v[0]), len(uvs))
+					}
+					indices = append(indices, int(v[0]))
+				}
+			} else {
+				for i := range uvs {
+					indices = append(indices, i)
+				}
+			}
+
+			// Vertices at the same position are the same 3D vertex, split
+			// at UV seams.
+			positions := make([]int, len(uvs))
+			for i := range positions {
+				positions[i] = -1
+			}
+			if positionAccessor, ok := prim.Attributes["POSITION"]; ok {
+				values, err := accessor(positionAccessor)
+				if err != nil {
+					return nil, err
+				}
+				for i, v := range values[:min(len(values), len(positions))] {
+					key := [3]float64{v[0], v[1], v[2]}
+					id, ok := welded[key]
+					if !ok {
+						id = len(m.Vertices)
+						welded[key] = id
+						m.Vertices = append(m.Vertices, key)
+					}
+					positions[i] = id
+				}
+			}
+
+			var triangles [][3]int
+			switch mode {
+			case gltfTriangles:
+				for i := 2; i < len(indices); i += 3 {
+					triangles = append(triangles, [3]int{indices[i-2], indices[i-1], indices[i]})
+				}
+			case gltfTriangleStrip:
+				for i := 2; i < len(indices); i++ {
+					triangles = append(triangles, [3]int{indices[i-2], indices[i-1], indices[i]})
+				}
+			case gltfTriangleFan:
+				for i := 2; i < len(indices); i++ {
+					triangles = append(triangles, [3]int{indices[0], indices[i-1], indices[i]})
+				}
+			}
+
+			base := len(m.UV)
+			for _, uv := range uvs {
+				if len(uv) < 2 {
+					return nil, errors.New("gltf: texture coordinates aren't VEC2")
+				}
+				m.UV = append(m.UV, [2]float64{uv[0], 1 - uv[1]})
+			}
+			for _, t := range triangles {
+				m.Triangles = append(m.Triangles, [3]int{base + t[0], base + t[1], base + t[2]})
+				m.Positions = append(m.Positions, [3]int{positions[t[0]], positions[t[1]], positions[t[2]]})
+			}
+		}
+	}
+	return m, nil
+}
+
+// splitGLB returns the JSON and binary chunks of a binary glTF file, the
+// latter nil if it has none.
+func splitGLB(data []byte) (jsonChunk, binChunk []byte, err error) {
+	if len(data) < 12 || binary.LittleEndian.Uint32(data[4:]) != 2 {
+		return nil, nil, errors.New("glb: unsupported version")
+	}
+	data = data[12:]
+	for len(data) >= 8 {
+		length := int(binary.LittleEndian.Uint32(data))
+		typ := string(data[4:8])
+		if length > len(data)-8 {
+			return nil, nil, errors.New("glb: truncated chunk")
+		}
+		switch typ {
+		case "JSON":
+			jsonChunk = data[8 : 8+length]
+		case "BIN\x00":
+			if binChunk == nil {
+				binChunk = data[8 : 8+length]
+			}
+		}
+		data = data[8+length:]
+	}
+	if jsonChunk == nil {
+		return nil, nil, errors.New("glb: missing JSON chunk")
+	}
+	return jsonChunk, binChunk, nil
+}
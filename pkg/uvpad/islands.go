@@ -0,0 +1,278 @@
+package uvpad
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// IslandMap assigns the pixels of a texture to its UV islands.
+type IslandMap struct {
+	Width, Height int
+	// Islands holds the island of every pixel from 0, -1 for the pixels
+	// outside every island.
+	Islands []int
+	// Count is the number of islands.
+	Count int
+	// Gutter is how the pixels outside every island are filled.
+	Gutter Gutter
+}
+
+// Gutter is how a dilation by islands fills the pixels outside every island.
+type Gutter int
+
+const (
+	// GutterDilate assigns them to the nearest island, which is dilated
+	// into them.
+	GutterDilate Gutter = iota
+	// GutterKeep leaves them unchanged.
+	GutterKeep
+	// GutterClamp copies the nearest pixel of the dilated islands into
+	// them, like clamped texture sampling at the edges of atlas rectangles.
+	GutterClamp
+)
+
+var gutterNames = []string{"dilate", "keep", "clamp"}
+
+func (g Gutter) String() string {
+	if int(g) < len(gutterNames) {
+		return gutterNames[g]
+	}
+	return fmt.Sprintf("Gutter(%d)", int(g))
+}
+
+// ParseGutter returns the gutter fill with the given name: dilate, keep or
+// clamp.
+func ParseGutter(name string) (Gutter, error) {
+	for i, n := range gutterNames {
+		if n == name {
+			return Gutter(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown gutter %q, expected dilate, keep or clamp", name)
+}
+
+// IslandsFromImage reads an island map from an image in which every island
+// has its own color. Transparent and black pixels are outside every island.
+func IslandsFromImage(img image.Image) *IslandMap {
+	bounds := img.Bounds()
+	m := &IslandMap{Width: bounds.Dx(), Height: bounds.Dy(), Islands: make([]int, bounds.Dx()*bounds.Dy())}
+	ids := map[color.NRGBA64]int{}
+	for y := range m.Height {
+		for x := range m.Width {
+			c := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+			if c.A == 0 || c.R == 0 && c.G == 0 && c.B == 0 {
+				m.Islands[y*m.Width+x] = -1
+				continue
+			}
+			c.A = 0xffff
+			id, ok := ids[c]
+			if !ok {
+				id = len(ids)
+				ids[c] = id
+			}
+			m.Islands[y*m.Width+x] = id
+		}
+	}
+	m.Count = len(ids)
+	return m
+}
+
+// Islands rasterizes the UV islands of the mesh, the groups of triangles
+// connected by shared texture coordinates, into a width by height island
+// map. Overlapping islands are assigned to the last triangle drawn.
+func (m *Mesh) Islands(width, height int) *IslandMap {
+	triangleIslands, count := m.triangleIslands()
+	islands := &IslandMap{Width: width, Height: height, Islands: make([]int, width*height), Count: count}
+	for i := range islands.Islands {
+		islands.Islands[i] = -1
+	}
+	for t, island := range triangleIslands {
+		m.rasterizeTriangle(t, width, height, func(x, y int) {
+			islands.Islands[y*width+x] = island
+		})
+	}
+	return islands
+}
+
+// Image returns the island of every pixel of a width by height image as a
+// 16-bit ID map: 0 for the pixels outside every island and the island plus 1
+// inside them. With GutterDilate the gutters take the ID of the nearest
+// island, the one that is dilated into them.
+func (m *IslandMap) Image(width, height int, opts Options) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, width, height))
+	regions := m.regions(width, height, opts)
+	for idx, island := range regions {
+		id := island + 1
+		img.Pix[idx*2], img.Pix[idx*2+1] = uint8(id>>8), uint8(id)
+	}
+	return img
+}
+
+// triangleIslands returns the island of every triangle, numbered from 0 in
+// the order of their first triangles, and the number of islands.
+func (m *Mesh) triangleIslands() ([]int, int) {
+	// Texture coordinates at the same position are the same vertex, as
+	// formats that split vertices at normal or material seams store them
+	// several times.
+	parent := make([]int, len(m.UV))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		parent[find(a)] = find(b)
+	}
+	first := map[[2]float64]int{}
+	for i, uv := range m.UV {
+		if j, ok := first[uv]; ok {
+			union(i, j)
+		} else {
+			first[uv] = i
+		}
+	}
+	for _, t := range m.Triangles {
+		union(t[0], t[1])
+		union(t[1], t[2])
+	}
+
+	islands := make([]int, len(m.Triangles))
+	ids := map[int]int{}
+	for i, t := range m.Triangles {
+		root := find(t[0])
+		id, ok := ids[root]
+		if !ok {
+			id = len(ids)
+			ids[root] = id
+		}
+		islands[i] = id
+	}
+	return islands, len(ids)
+}
+
+// regions returns the island of every pixel of a width by height image,
+// scaling the map with nearest neighbour sampling. With GutterDilate the
+// pixels outside every island are assigned to the nearest one, so that
+// every gutter belongs to a single island. It returns nil for maps without
+// islands.
+func (m *IslandMap) regions(width, height int, opts Options) []int {
+	regions := make([]int, width*height)
+	inside := make([]bool, width*height)
+	labelled := false
+	for y := range height {
+		my := y * m.Height / height
+		for x := range width {
+			mx := x * m.Width / width
+			regions[y*width+x] = m.Islands[my*m.Width+mx]
+			inside[y*width+x] = regions[y*width+x] >= 0
+			labelled = labelled || inside[y*width+x]
+		}
+	}
+	if !labelled {
+		return nil
+	}
+	if m.Gutter == GutterDilate {
+		fillNearestIsland(regions, inside, width, height, opts, func(idx, nearest int) {
+			regions[idx] = regions[nearest]
+		})
+	}
+	return regions
+}
+
+// fillNearestIsland calls fill for every pixel outside the islands with
+// the index of the nearest pixel inside them.
+func fillNearestIsland(regions []int, inside []bool, width, height int, opts Options, fill func(idx, nearest int)) {
+	opts.Progress = nil
+	for idx, seed := range distanceTransform(width, height, inside, opts) {
+		if !inside[idx] && seed != noSeed {
+			fill(idx, int(seed))
+		}
+	}
+}
+
+// processIslands runs process on every island of opts.Islands on its own,
+// with only the seeds of the island, so that no island is dilated into the
+// gutter of another. Each pixel takes the result of the island whose region
+// holds it, cropped to the bounds of the region unless the image wraps. The
+// pixels outside every island are then filled as islands.Gutter says.
+func processIslands[T Sample](src *Buffer[T], opts Options, process func(Image, Options) Image) *Buffer[T] {
+	islands := opts.Islands
+	opts.Islands = nil
+	width, height := src.Width, src.Height
+	regions := islands.regions(width, height, opts)
+	if regions == nil {
+		return process(src, opts).(*Buffer[T])
+	}
+
+	bounds := make([]image.Rectangle, islands.Count)
+	for idx, island := range regions {
+		if island < 0 {
+			continue
+		}
+		r := image.Rect(idx%width, idx/width, idx%width+1, idx/width+1)
+		if bounds[island].Empty() {
+			bounds[island] = r
+		} else {
+			bounds[island] = bounds[island].Union(r)
+		}
+	}
+
+	// Pixels outside every island are kept unless they are filled.
+	out := src.Clone()
+	progress := opts.Progress
+	for island, r := range bounds {
+		if r.Empty() {
+			continue
+		}
+		if opts.Edge == EdgeWrap {
+			r = image.Rect(0, 0, width, height)
+		}
+
+		// Pixels of other regions aren't seeds of the island.
+		crop := NewBuffer[T](r.Dx(), r.Dy())
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i, j := (y*width+x)*4, ((y-r.Min.Y)*crop.Width+x-r.Min.X)*4
+				copy(crop.Pix[j:j+4], src.Pix[i:i+4])
+				if regions[y*width+x] != island {
+					crop.Pix[j+3] = 0
+				}
+			}
+		}
+
+		if progress != nil {
+			done := float64(island) / float64(len(bounds))
+			opts.Progress = func(fraction float64) { progress(done + fraction/float64(len(bounds))) }
+		}
+		result := process(crop, opts).(*Buffer[T])
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if regions[y*width+x] == island {
+					i, j := (y*width+x)*4, ((y-r.Min.Y)*crop.Width+x-r.Min.X)*4
+					copy(out.Pix[i:i+4], result.Pix[j:j+4])
+				}
+			}
+		}
+		if result != crop {
+			result.release()
+		}
+		crop.release()
+	}
+
+	if islands.Gutter == GutterClamp {
+		inside := make([]bool, len(regions))
+		for idx, island := range regions {
+			inside[idx] = island >= 0
+		}
+		fillNearestIsland(regions, inside, width, height, opts, func(idx, nearest int) {
+			copy(out.Pix[idx*4:idx*4+4], out.Pix[nearest*4:nearest*4+4])
+		})
+	}
+	return out
+}
@@ -0,0 +1,373 @@
+package uvpad
+
+import (
+	"image"
+	"math"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// noSeed marks the pixels without a seed in a map of nearest seeds, which
+// holds the index y*width+x of the nearest seed of every pixel as an int32,
+// a quarter of the memory of a pair of ints.
+const noSeed = -1
+
+// seedOffset returns the offset from the pixel at x, y to seed, the shortest
+// one across the border with EdgeWrap.
+func seedOffset(x, y int, seed int32, width, height int, edge Edge) (dx, dy int) {
+	s := int(seed)
+	return edge.offset(x-s%width, width), edge.offset(y-s/width, height)
+}
+
+func init() {
+	Register("jfa", nearestAlgorithm{generic{
+		processJFA[uint8], processJFA[uint16], processJFA[float32],
+		func(int64) int64 {
+			// Seed mask plus two generations of nearest seeds.
+			return 1 + 2*int64(unsafe.Sizeof(int32(0)))
+		},
+	}, jumpFlood})
+}
+
+// processJFA is the paint.net style dilation: every transparent pixel takes
+// the color of its nearest opaque pixel, found with a parallel jump flood.
+func processJFA[T Sample](input *Buffer[T], opts Options) *Buffer[T] {
+	opaqueMask := seedMask(input, opts)
+	nearest := boundedTransform(input.Width, input.Height, opaqueMask, opts, jumpFlood)
+	output := fillNearest(input, opaqueMask, nearest, opts)
+	maskPool.put(opaqueMask)
+	seedPool.put(nearest)
+	return output
+}
+
+// seedMask marks the pixels whose color is dilated.
+func seedMask[T Sample](input *Buffer[T], opts Options) []bool {
+	seed := seedAlpha[T](opts)
+	mask := maskPool.get(input.Width * input.Height)
+	for idx := range mask {
+		mask[idx] = input.Pix[idx*4+3] >= seed
+	}
+	return mask
+}
+
+// fillNearest returns a copy of input where every pixel outside the seed mask
+// takes the color of its nearest seed, or is cleared if it has none within
+// the radius. With opts.Blend above one the colors of that many nearest seeds
+// are blended instead.
+func fillNearest[T Sample](input *Buffer[T], opaqueMask []bool, nearest []int32, opts Options) *Buffer[T] {
+	width, height := input.Width, input.Height
+	if opts.Blend > 1 {
+		lists := kNearest(width, height, nearest, opts)
+		output := blendNearest(input, opaqueMask, lists, opts)
+		seedPool.put(lists)
+		return output
+	}
+	opaque := opaqueValue[T]()
+
+	output := input.Clone()
+	limit := opts.Metric.limit(opts.Radius)
+	for idx, seed := range nearest {
+		if opaqueMask[idx] {
+			continue
+		}
+
+		pixel := output.Pix[idx*4 : idx*4+4]
+		if seed != noSeed {
+			if dx, dy := seedOffset(idx%width, idx/width, seed, width, height, opts.Edge); opts.Metric.within(dx, dy, limit) {
+				copy(pixel, input.Pix[int(seed)*4:][:3])
+				pixel[3] = opaque
+				continue
+			}
+		}
+		clear(pixel)
+	}
+
+	return output
+}
+
+func jumpFlood(width, height int, opaqueMask []bool, opts Options) []int32 {
+	// The distances to the seeds are measured again from their indices
+	// rather than stored, which keeps the working set of the flood small.
+	nearest := seedPool.get(width * height)
+	for idx, opaque := range opaqueMask {
+		nearest[idx] = noSeed
+		if opaque {
+			nearest[idx] = int32(idx)
+		}
+	}
+
+	g := grid{width, height, 0, width, height}
+	if opts.JFA.Hierarchical && max(width, height) > hierarchicalBase {
+		return hierarchicalJumpFlood(g, nearest, opts)
+	}
+	return flood(g, nearest, opts.JFA.steps(g.maxSteps(opts.Radius)), opts)
+}
+
+// grid is the lattice a jump flood runs on: width×height cells of 1<<shift
+// pixels on a side of an image of imageWidth×imageHeight pixels. The seeds
+// are pixels of the image whatever the size of the cells, and distances are
+// measured in pixels from the center of the cells.
+type grid struct {
+	width, height           int
+	shift                   int
+	imageWidth, imageHeight int
+}
+
+// offset returns the offset from the center of the cell at x, y to seed.
+func (g grid) offset(x, y int, seed int32, edge Edge) (dx, dy int) {
+	half := (1 << g.shift) >> 1
+	return seedOffset(x<<g.shift+half, y<<g.shift+half, seed, g.imageWidth, g.imageHeight, edge)
+}
+
+// maxSteps returns the number of steps that carry seeds across the grid,
+// or to the radius.
+func (g grid) maxSteps(radius float64) int {
+	maxSteps := int(math.Ceil(math.Log2(float64(math.Max(float64(g.width), float64(g.height)))))) * 2
+	if radius > 0 {
+		// Steps 1..n reach seeds up to n(n+1)/2 cells away, so steps longer
+		// than the radius only find seeds that are discarded.
+		maxSteps = min(maxSteps, int(math.Ceil(math.Ldexp(radius, -g.shift)))+1)
+	}
+	return maxSteps
+}
+
+// coarser returns the grid of cells twice as large.
+func (g grid) coarser() grid {
+	return grid{(g.width + 1) / 2, (g.height + 1) / 2, g.shift + 1, g.imageWidth, g.imageHeight}
+}
+
+// hierarchicalBase is the size up to which the hierarchical jump flood
+// floods the grid itself rather than a coarser one.
+const hierarchicalBase = 512
+
+// hierarchicalJumpFlood floods a pyramid of grids of doubling cell sizes
+// from the coarsest, where few steps carry seeds across the image, down to
+// the pixels. Every finer grid starts from the nearest seeds of the cells
+// around its parent cell and corrects them with steps 2 and 1 and the final
+// passes of the variant. seeds holds the seed of every pixel, or noSeed.
+func hierarchicalJumpFlood(g grid, seeds []int32, opts Options) []int32 {
+	// The seed of every cell of the coarser grids is the seed of its four
+	// cells nearest to its center.
+	grids, levels := []grid{g}, [][]int32{seeds}
+	for max(g.width, g.height) > hierarchicalBase {
+		fine := levels[len(levels)-1]
+		fineGrid := g
+		g = g.coarser()
+		coarse := seedPool.get(g.width * g.height)
+		forBands(g.height, opts, func(start, end int) {
+			for y := start; y < end; y++ {
+				for x := 0; x < g.width; x++ {
+					best := int32(noSeed)
+					var bestDistance int64
+					for fy := 2 * y; fy < min(2*y+2, fineGrid.height); fy++ {
+						for fx := 2 * x; fx < min(2*x+2, fineGrid.width); fx++ {
+							seed := fine[fy*fineGrid.width+fx]
+							if seed == noSeed {
+								continue
+							}
+							distance := opts.Metric.distance(g.offset(x, y, seed, opts.Edge))
+							if closer(seed, distance, best, bestDistance) {
+								best, bestDistance = seed, distance
+							}
+						}
+					}
+					coarse[y*g.width+x] = best
+				}
+			}
+		})
+		grids, levels = append(grids, g), append(levels, coarse)
+	}
+
+	progress := opts.Progress
+	stage := func(i int) Options {
+		stageOpts := opts
+		if progress != nil {
+			stageOpts.Progress = func(fraction float64) { progress((float64(i) + fraction) / float64(len(grids))) }
+		}
+		return stageOpts
+	}
+
+	top := len(grids) - 1
+	nearest := seedPool.get(len(levels[top]))
+	copy(nearest, levels[top])
+	nearest = flood(grids[top], nearest, opts.JFA.steps(grids[top].maxSteps(opts.Radius)), stage(0))
+
+	steps := []int{2, 1}
+	for step := opts.JFA.Post; step >= 1; step-- {
+		steps = append(steps, step)
+	}
+	for i := top - 1; i >= 0; i-- {
+		g, parent := grids[i], grids[i+1]
+		own, coarse := levels[i], nearest
+		nearest = seedPool.get(g.width * g.height)
+		forBands(g.height, opts, func(start, end int) {
+			for y := start; y < end; y++ {
+				for x := 0; x < g.width; x++ {
+					best := own[y*g.width+x]
+					var bestDistance int64
+					if best != noSeed {
+						bestDistance = opts.Metric.distance(g.offset(x, y, best, opts.Edge))
+					}
+					if best != noSeed && bestDistance == 0 {
+						// A seed is its own nearest.
+						nearest[y*g.width+x] = best
+						continue
+					}
+					previous := int32(noSeed)
+					for dy := -1; dy <= 1; dy++ {
+						for dx := -1; dx <= 1; dx++ {
+							px, okx := opts.Edge.resolve(x/2+dx, parent.width)
+							py, oky := opts.Edge.resolve(y/2+dy, parent.height)
+							if !okx || !oky {
+								continue
+							}
+							seed := coarse[py*parent.width+px]
+							if seed == noSeed || seed == previous {
+								continue
+							}
+							previous = seed
+							// The seeds of the four cells of the parent
+							// cell of seed, one of which is nearer than
+							// the seed that stands for all of them.
+							sx, sy := int(seed)%g.imageWidth>>parent.shift, int(seed)/g.imageWidth>>parent.shift
+							for cy := 2 * sy; cy < min(2*sy+2, g.height); cy++ {
+								for cx := 2 * sx; cx < min(2*sx+2, g.width); cx++ {
+									seed := own[cy*g.width+cx]
+									if seed == noSeed {
+										continue
+									}
+									distance := opts.Metric.distance(g.offset(x, y, seed, opts.Edge))
+									if closer(seed, distance, best, bestDistance) {
+										best, bestDistance = seed, distance
+									}
+								}
+							}
+						}
+					}
+					nearest[y*g.width+x] = best
+				}
+			}
+		})
+		seedPool.put(coarse)
+		nearest = flood(g, nearest, steps, stage(top-i))
+	}
+
+	for _, level := range levels {
+		seedPool.put(level)
+	}
+	return nearest
+}
+
+// flood runs the jump flood passes of steps on the seeds of the cells of g,
+// reusing nearest, and returns the seeds of the last pass.
+func flood(g grid, nearest []int32, steps []int, opts Options) []int32 {
+	columns, rows := (g.width+floodBlock-1)/floodBlock, (g.height+floodBlock-1)/floodBlock
+	block := func(b int) image.Rectangle {
+		x, y := b%columns*floodBlock, b/columns*floodBlock
+		return image.Rect(x, y, min(x+floodBlock, g.width), min(y+floodBlock, g.height))
+	}
+
+	// Every step reads the seeds of the previous one and writes all of its
+	// own, so two buffers are swapped rather than copied.
+	next := seedPool.get(len(nearest))
+	for i, step := range steps {
+		if opts.checkpoint != nil {
+			// Cooperative mode: small bands on the calling goroutine so
+			// every time slice ends promptly.
+			for start := 0; start < g.height; start += cooperativeRows {
+				end := min(start+cooperativeRows, g.height)
+				processJumpFlood(g, nearest, next, step, image.Rect(0, start, g.width, end), opts)
+				opts.yield()
+			}
+			nearest, next = next, nearest
+			opts.progress(float64(i+1) / float64(len(steps)))
+			continue
+		}
+
+		// The workers take the blocks in turn, row by row, so the rows
+		// around the blocks being flooded are shared in the cache.
+		var wg sync.WaitGroup
+		var taken atomic.Int64
+		for range min(opts.threads(), columns*rows) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for b := int(taken.Add(1) - 1); b < columns*rows; b = int(taken.Add(1) - 1) {
+					processJumpFlood(g, nearest, next, step, block(b), opts)
+				}
+			}()
+		}
+
+		wg.Wait()
+		nearest, next = next, nearest
+		opts.progress(float64(i+1) / float64(len(steps)))
+	}
+	seedPool.put(next)
+
+	return nearest
+}
+
+// closer reports whether the seed p at distance d is nearer than best at
+// bestDistance, or best is noSeed. Equidistant seeds are ordered by their
+// index, row by row, so the result doesn't depend on the order in which the
+// candidates are found and padded textures are byte-stable.
+func closer(p int32, d int64, best int32, bestDistance int64) bool {
+	if best == noSeed {
+		return true
+	}
+	if d != bestDistance {
+		return d < bestDistance
+	}
+	return p < best
+}
+
+// floodBlock is the size of the blocks of cells a jump flood pass processes
+// at once: the rows of a block and those step cells above and below it fit
+// in the L2 cache.
+const floodBlock = 256
+
+// processJumpFlood writes to next the nearest of the seeds of previous at
+// and step cells around every cell of r, a block of g.
+func processJumpFlood(g grid, previous, next []int32, step int, r image.Rectangle, opts Options) {
+	edge, metric := opts.Edge, opts.Metric
+	width, height := g.width, g.height
+	imageWidth, imageHeight := g.imageWidth, g.imageHeight
+	shift, half := g.shift, (1<<g.shift)>>1
+	neighbours := []struct{ dx, dy int }{
+		{-step, -step}, {0, -step}, {step, -step},
+		{-step, 0}, {step, 0},
+		{-step, step}, {0, step}, {step, step},
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		py := y<<shift + half
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// The center of the cell, in pixels.
+			px := x<<shift + half
+			idx := y*width + x
+			best := previous[idx]
+			var bestDistance int64
+			if best != noSeed {
+				bestDistance = metric.distance(seedOffset(px, py, best, imageWidth, imageHeight, edge))
+			}
+
+			for _, neighbour := range neighbours {
+				nx, okx := edge.resolve(x+neighbour.dx, width)
+				ny, oky := edge.resolve(y+neighbour.dy, height)
+				if !okx || !oky {
+					continue
+				}
+				seed := previous[ny*width+nx]
+				if seed == noSeed {
+					continue
+				}
+				distance := metric.distance(seedOffset(px, py, seed, imageWidth, imageHeight, edge))
+				if closer(seed, distance, best, bestDistance) {
+					best, bestDistance = seed, distance
+				}
+			}
+			next[idx] = best
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package uvpad
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JFAVariant adds the standard accuracy passes to the jump flood, which fix
+// most of the pixels it assigns a wrong seed for a few extra passes.
+type JFAVariant struct {
+	// Pre floods with step 1 before the main passes (1+JFA).
+	Pre bool
+	// Post is the number of final passes: 1 for a pass with step 1 (JFA+1),
+	// 2 for passes with steps 2 and 1 (JFA+2).
+	Post int
+	// Hierarchical floods a half resolution copy of the seeds first,
+	// recursively, and refines its seeds with a few passes at full
+	// resolution (HJFA). It is faster on large images, but more pixels get
+	// a seed slightly farther than their nearest one than with the passes
+	// of a single resolution.
+	Hierarchical bool
+}
+
+// ParseJFAVariant parses a variant name: jfa, 1+jfa, jfa+1, jfa+2, 1+jfa+1 or
+// 1+jfa+2, with hjfa in place of jfa for the hierarchical flood.
+func ParseJFAVariant(name string) (JFAVariant, error) {
+	var v JFAVariant
+	rest, pre := strings.CutPrefix(name, "1+")
+	v.Pre = pre
+	rest, v.Hierarchical = strings.CutPrefix(rest, "h")
+	switch rest {
+	case "jfa":
+	case "jfa+1":
+		v.Post = 1
+	case "jfa+2":
+		v.Post = 2
+	default:
+		return JFAVariant{}, fmt.Errorf("unknown jump flood variant %q, expected jfa, 1+jfa, jfa+1, jfa+2, 1+jfa+1 or 1+jfa+2, or hjfa in place of jfa", name)
+	}
+	return v, nil
+}
+
+func (v JFAVariant) String() string {
+	name := "jfa"
+	if v.Hierarchical {
+		name = "hjfa"
+	}
+	if v.Pre {
+		name = "1+" + name
+	}
+	if v.Post > 0 {
+		name += fmt.Sprintf("+%d", v.Post)
+	}
+	return name
+}
+
+// steps returns the step lengths of the jump flood passes around the main
+// passes with steps 1 to maxSteps-1.
+func (v JFAVariant) steps(maxSteps int) []int {
+	var steps []int
+	if v.Pre {
+		steps = append(steps, 1)
+	}
+	for step := 1; step < maxSteps; step++ {
+		steps = append(steps, step)
+	}
+	for step := v.Post; step >= 1; step-- {
+		steps = append(steps, step)
+	}
+	return steps
+}
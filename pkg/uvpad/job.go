@@ -0,0 +1,110 @@
+package uvpad
+
+import (
+	"errors"
+	"time"
+)
+
+// cooperativeRows is the number of rows processed between checkpoints by
+// algorithms that are otherwise processed in parallel.
+const cooperativeRows = 16
+
+var errCanceled = errors.New("uvpad: job canceled")
+
+// Job is a dilation that runs incrementally in bounded time slices, so that
+// applications such as game editors can dilate large textures on their main
+// thread without hitching.
+//
+//	job := uvpad.NewJob(alg, src, opts)
+//	for !job.Step(4 * time.Millisecond) {
+//		// render a frame
+//	}
+//	result := job.Result()
+type Job struct {
+	alg  Algorithm
+	src  Image
+	opts Options
+
+	started bool
+	done    bool
+	result  Image
+
+	resume chan time.Time
+	paused chan bool
+}
+
+// NewJob prepares a dilation of src. No work happens until Step is called.
+func NewJob(alg Algorithm, src Image, opts Options) *Job {
+	return &Job{
+		alg:    alg,
+		src:    src,
+		opts:   opts,
+		resume: make(chan time.Time),
+		paused: make(chan bool),
+	}
+}
+
+// Step runs the dilation for roughly budget and reports whether it finished.
+// A step may overrun the budget by one unit of work of the algorithm, which
+// is a handful of rows.
+func (j *Job) Step(budget time.Duration) (done bool) {
+	if j.done {
+		return true
+	}
+
+	deadline := time.Now().Add(budget)
+	if !j.started {
+		j.started = true
+		go j.run(deadline)
+	} else {
+		j.resume <- deadline
+	}
+
+	j.done = <-j.paused
+	return j.done
+}
+
+// Result returns the dilated image once Step has reported completion, and nil
+// before that.
+func (j *Job) Result() Image {
+	if !j.done {
+		return nil
+	}
+	return j.result
+}
+
+// Cancel abandons an unfinished job and releases its resources. The job must
+// not be stepped afterwards.
+func (j *Job) Cancel() {
+	if j.started && !j.done {
+		close(j.resume)
+		<-j.paused
+	}
+	j.done = true
+	j.result = nil
+}
+
+func (j *Job) run(deadline time.Time) {
+	defer func() {
+		if r := recover(); r != nil && r != errCanceled {
+			panic(r)
+		}
+		j.paused <- true
+	}()
+
+	opts := j.opts
+	opts.checkpoint = func() {
+		if time.Now().Before(deadline) {
+			return
+		}
+		j.paused <- false
+
+		next, ok := <-j.resume
+		if !ok {
+			panic(errCanceled)
+		}
+		deadline = next
+	}
+
+	j.result = j.alg.Process(j.src, opts)
+}
@@ -0,0 +1,44 @@
+package uvpad
+
+import "image"
+
+// maskCoverage samples a width×height grid of coverage values from 0 to 1
+// from mask: its alpha if it has transparent pixels, its luminance otherwise.
+// Masks of another size are scaled with nearest neighbour sampling.
+func maskCoverage(mask image.Image, width, height int) []float64 {
+	switch buf := FromImage(mask).(type) {
+	case *Buffer[uint8]:
+		return bufferCoverage(buf, width, height)
+	case *Buffer[uint16]:
+		return bufferCoverage(buf, width, height)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+func bufferCoverage[T uint8 | uint16](mask *Buffer[T], width, height int) []float64 {
+	opaque := opaqueValue[T]()
+	useAlpha := false
+	for i := 3; i < len(mask.Pix) && !useAlpha; i += 4 {
+		useAlpha = mask.Pix[i] != opaque
+	}
+
+	// Samples are widened to 16 bits, as color.Color.RGBA returns them.
+	scale := uint32(0xffff / uint32(opaque))
+	coverage := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		my := y * mask.Height / height
+		for x := 0; x < width; x++ {
+			mx := x * mask.Width / width
+			p := mask.Pix[(my*mask.Width+mx)*4:][:4]
+			if useAlpha {
+				coverage[y*width+x] = float64(uint32(p[3])*scale) / 0xffff
+			} else {
+				// The luminance of color.Gray16Model.
+				r, g, b := uint32(p[0])*scale, uint32(p[1])*scale, uint32(p[2])*scale
+				coverage[y*width+x] = float64((19595*r+38470*g+7471*b+1<<15)>>16) / 0xffff
+			}
+		}
+	}
+	return coverage
+}
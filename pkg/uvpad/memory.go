@@ -0,0 +1,68 @@
+package uvpad
+
+import (
+	"image"
+	"unsafe"
+)
+
+// MemoryEstimator is implemented by algorithms that can predict their peak
+// memory use.
+type MemoryEstimator interface {
+	// EstimateMemory returns the peak number of bytes allocated while
+	// processing a width×height image with sampleSize bytes per channel,
+	// including the input and output buffers.
+	EstimateMemory(width, height, sampleSize int) int64
+}
+
+// EstimateMemory returns the expected peak memory in bytes for running alg
+// on a width×height image with sampleSize bytes per channel. Algorithms that
+// don't implement MemoryEstimator are assumed to need only an input and an
+// output buffer.
+func EstimateMemory(alg Algorithm, width, height, sampleSize int, opts Options) int64 {
+	pixels := int64(width) * int64(height)
+	var extra int64
+	if opts.Premultiplied {
+		// The straight alpha copy of the input.
+		extra += pixels * 4 * int64(sampleSize)
+	}
+	if opts.Packed {
+		// The result of the first run, held while the masked channels run.
+		extra += pixels * 4 * int64(sampleSize)
+	}
+	if opts.changesCoverage() || opts.ChannelMasks != [3]image.Image{} {
+		// The copy of the input with the coverage, and the masks and
+		// distance transform of the morphological operations.
+		extra += pixels * (4*int64(sampleSize) + 8)
+		if opts.Open > 0 || opts.Close > 0 || opts.Erode > 0 {
+			extra += pixels * (2 + 2*int64(unsafe.Sizeof(int32(0))))
+		}
+	}
+	if opts.Blend > 1 {
+		// Two generations of nearest seed lists.
+		extra += 2 * pixels * int64(opts.Blend) * int64(unsafe.Sizeof(int32(0)))
+	}
+	if opts.FadeDistance > 0 || opts.Feather > 0 {
+		// The distance transform of the seeds and the distances.
+		extra += pixels * (1 + 2*int64(unsafe.Sizeof(int32(0))) + 8)
+	}
+	if opts.tiled(width, height) {
+		// The assembled output, while the algorithm runs on a single tile.
+		extra += pixels * 4 * int64(sampleSize)
+		side := opts.Tile + 2*opts.tileMargin()
+		width, height = min(width, side), min(height, side)
+	}
+	if opts.Bias != 0 || opts.NormalMap || opts.ColorSpace == ColorSpaceLinear {
+		// The signed and linear paths convert to float buffers on the way in
+		// and out.
+		buffers := 2 * pixels * 4 * int64(sampleSize)
+		return buffers + extra + estimate(alg, width, height, 4)
+	}
+	return extra + estimate(alg, width, height, sampleSize)
+}
+
+func estimate(alg Algorithm, width, height, sampleSize int) int64 {
+	if e, ok := alg.(MemoryEstimator); ok {
+		return e.EstimateMemory(width, height, sampleSize)
+	}
+	return 2 * int64(width) * int64(height) * 4 * int64(sampleSize)
+}
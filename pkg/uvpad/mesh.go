@@ -0,0 +1,284 @@
+package uvpad
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Mesh is the UV layout of a model: its texture coordinates and the
+// triangles between them.
+type Mesh struct {
+	// UV are the texture coordinates, with v pointing up as in the model
+	// formats.
+	UV [][2]float64
+	// Triangles index UV.
+	Triangles [][3]int
+	// Positions identify the 3D vertices of the corners of Triangles, equal
+	// where triangles share a vertex across UV seams, -1 where unknown.
+	Positions [][3]int
+	// Vertices are the coordinates of the 3D vertices that Positions index.
+	Vertices [][3]float64
+}
+
+// MeshFilter selects the parts of a model whose UV layout is read, so that
+// it matches the texture being padded. Each selector is a name or an index
+// counted from 0, and empty selects everything.
+type MeshFilter struct {
+	// Mesh selects a mesh, an object (o) of OBJ models.
+	Mesh string
+	// Primitive selects a primitive of the selected glTF meshes by index.
+	Primitive string
+	// Material selects the faces of a material, by their usemtl name or
+	// order of first use in OBJ models.
+	Material string
+	// UVSet selects the set of texture coordinates by name or index, the
+	// first if empty: a TEXCOORD_n attribute of glTF models or a UV layer
+	// of FBX models. OBJ models have a single set.
+	UVSet string
+}
+
+// selects reports whether selector selects the part with the given name and
+// index.
+func selects(selector, name string, index int) bool {
+	return selector == "" || selector == name || selector == strconv.Itoa(index)
+}
+
+// ParseOBJ reads the UV layout of the faces of a Wavefront OBJ model that
+// match filter. Polygons are split into triangle fans and faces without
+// texture coordinates are skipped.
+func ParseOBJ(r io.Reader, filter MeshFilter) (*Mesh, error) {
+	if !selects(filter.UVSet, "", 0) {
+		return nil, fmt.Errorf("obj: no UV set %s, OBJ models have a single UV set", filter.UVSet)
+	}
+	m := &Mesh{}
+	var objects, materials []string
+	object, material := "", ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "o", "usemtl":
+			name := strings.Join(fields[1:], " ")
+			names := &objects
+			if fields[0] == "usemtl" {
+				names = &materials
+				material = name
+			} else {
+				object = name
+			}
+			if !slices.Contains(*names, name) {
+				*names = append(*names, name)
+			}
+		case "v":
+			var v [3]float64
+			for i, s := range fields[1:min(4, len(fields))] {
+				v[i], _ = strconv.ParseFloat(s, 64)
+			}
+			m.Vertices = append(m.Vertices, v)
+		case "vt":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("obj line %d: texture coordinate without u", line)
+			}
+			var uv [2]float64
+			for i, s := range fields[1:min(3, len(fields))] {
+				v, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return nil, fmt.Errorf("obj line %d: invalid texture coordinate %q", line, s)
+				}
+				uv[i] = v
+			}
+			m.UV = append(m.UV, uv)
+		case "f":
+			if !selects(filter.Mesh, object, slices.Index(objects, object)) ||
+				!selects(filter.Material, material, slices.Index(materials, material)) {
+				continue
+			}
+			var face, positions []int
+			for _, vertex := range fields[1:] {
+				// Vertices are v, v/vt, v/vt/vn or v//vn.
+				parts := strings.Split(vertex, "/")
+				if len(parts) < 2 || parts[1] == "" {
+					face = nil
+					break
+				}
+				idx, err := objIndex(parts[1], len(m.UV))
+				if err != nil {
+					return nil, fmt.Errorf("obj line %d: invalid texture coordinate %q", line, parts[1])
+				}
+				// Only the UVs are required, faces with invalid vertices
+				// aren't stitched.
+				position, err := objIndex(parts[0], len(m.Vertices))
+				if err != nil {
+					position = -1
+				}
+				face = append(face, idx)
+				positions = append(positions, position)
+			}
+			for i := 2; i < len(face); i++ {
+				m.Triangles = append(m.Triangles, [3]int{face[0], face[i-1], face[i]})
+				m.Positions = append(m.Positions, [3]int{positions[0], positions[i-1], positions[i]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// objIndex resolves a 1-based OBJ index into count elements, negative
+// indices counting back from the last one.
+func objIndex(s string, count int) (int, error) {
+	idx, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if idx < 0 {
+		idx += count
+	} else {
+		idx--
+	}
+	if idx < 0 || idx >= count {
+		return 0, fmt.Errorf("index %s out of range", s)
+	}
+	return idx, nil
+}
+
+// Rasterize returns the coverage of the UV triangles in a width by height
+// texture: 255 for the texels whose center lies in a triangle, as bakers
+// fill them, 0 elsewhere. Used as Options.Mask, it seeds the dilation by the
+// UV layout of fully opaque bakes.
+func (m *Mesh) Rasterize(width, height int) *image.Gray {
+	mask := image.NewGray(image.Rect(0, 0, width, height))
+	for t := range m.Triangles {
+		m.rasterizeTriangle(t, width, height, func(x, y int) {
+			mask.Pix[y*mask.Stride+x] = 255
+		})
+	}
+	return mask
+}
+
+// Coverage returns the anti-aliased coverage of the UV triangles in a width
+// by height texture: the fraction of samples by samples points of every
+// texel that lie in a triangle, from 0 to 255.
+func (m *Mesh) Coverage(width, height, samples int) *image.Gray {
+	// Overlapping and adjacent triangles share samples, so every sample is
+	// counted once.
+	sampledWidth, sampledHeight := width*samples, height*samples
+	covered := make([]uint64, (sampledWidth*sampledHeight+63)/64)
+	for t := range m.Triangles {
+		m.rasterizeTriangle(t, sampledWidth, sampledHeight, func(x, y int) {
+			i := y*sampledWidth + x
+			covered[i/64] |= 1 << (i % 64)
+		})
+	}
+
+	mask := image.NewGray(image.Rect(0, 0, width, height))
+	scale := 255 / float64(samples*samples)
+	for y := range height {
+		for x := range width {
+			count := 0
+			for sy := y * samples; sy < (y+1)*samples; sy++ {
+				for sx := x * samples; sx < (x+1)*samples; sx++ {
+					i := sy*sampledWidth + sx
+					count += int(covered[i/64] >> (i % 64) & 1)
+				}
+			}
+			mask.Pix[y*mask.Stride+x] = uint8(math.Round(float64(count) * scale))
+		}
+	}
+	return mask
+}
+
+// DrawWireframe draws the edges of the UV triangles of the mesh over img in
+// color c, blended by its alpha, so the UV layout can be compared with the
+// texture it was scaled to.
+func (m *Mesh) DrawWireframe(img *image.NRGBA, c Color) {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	// Edges shared by triangles and their ends are drawn once, so that
+	// translucent colors are blended evenly.
+	drawn := make([]bool, width*height)
+	plot := func(x, y int) {
+		if x < 0 || y < 0 || x >= width || y >= height || drawn[y*width+x] {
+			return
+		}
+		drawn[y*width+x] = true
+		p := img.Pix[y*img.Stride+x*4 : y*img.Stride+x*4+4]
+		for i := range 3 {
+			p[i] = uint8(math.Round(float64(p[i])*(1-c[3]) + 255*c[i]*c[3]))
+		}
+		p[3] = uint8(math.Round(float64(p[3])*(1-c[3]) + 255*c[3]))
+	}
+	for t := range m.Triangles {
+		for i := range 3 {
+			a, b := m.pixel(m.Triangles[t][i], width, height), m.pixel(m.Triangles[t][(i+1)%3], width, height)
+			// The UV space is sampled at pixel centers.
+			a[0], a[1], b[0], b[1] = a[0]-0.5, a[1]-0.5, b[0]-0.5, b[1]-0.5
+			steps := int(math.Ceil(max(math.Abs(b[0]-a[0]), math.Abs(b[1]-a[1]))))
+			for s := 0; s <= steps; s++ {
+				f := 0.0
+				if steps > 0 {
+					f = float64(s) / float64(steps)
+				}
+				plot(int(math.Round(a[0]+(b[0]-a[0])*f)), int(math.Round(a[1]+(b[1]-a[1])*f)))
+			}
+		}
+	}
+}
+
+// pixel returns the position of a texture coordinate in a width by height
+// texture, in pixels from its top left corner.
+func (m *Mesh) pixel(uv, width, height int) [2]float64 {
+	return [2]float64{m.UV[uv][0] * float64(width), (1 - m.UV[uv][1]) * float64(height)}
+}
+
+// rasterizeTriangle calls fill for the pixels of the triangle t in a width
+// by height texture.
+func (m *Mesh) rasterizeTriangle(t, width, height int, fill func(x, y int)) {
+	corners := m.Triangles[t]
+	p := [3][2]float64{m.pixel(corners[0], width, height), m.pixel(corners[1], width, height), m.pixel(corners[2], width, height)}
+	rasterizeTriangle(p, width, height, fill)
+}
+
+// rasterizeTriangle calls fill for the pixels of a width by height image
+// whose centers lie in the triangle p, in pixel coordinates, including its
+// edges.
+func rasterizeTriangle(p [3][2]float64, width, height int, fill func(x, y int)) {
+	area := cross(p[0], p[1], p[2])
+	if area == 0 {
+		return
+	}
+
+	minX := max(0, int(math.Floor(min(p[0][0], p[1][0], p[2][0])-0.5)))
+	maxX := min(width-1, int(math.Ceil(max(p[0][0], p[1][0], p[2][0])-0.5)))
+	minY := max(0, int(math.Floor(min(p[0][1], p[1][1], p[2][1])-0.5)))
+	maxY := min(height-1, int(math.Ceil(max(p[0][1], p[1][1], p[2][1])-0.5)))
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			c := [2]float64{float64(x) + 0.5, float64(y) + 0.5}
+			w0, w1, w2 := cross(p[1], p[2], c), cross(p[2], p[0], c), cross(p[0], p[1], c)
+			// Either winding is inside when the signs agree with the area.
+			if area < 0 {
+				w0, w1, w2 = -w0, -w1, -w2
+			}
+			if w0 >= 0 && w1 >= 0 && w2 >= 0 {
+				fill(x, y)
+			}
+		}
+	}
+}
+
+// cross returns twice the signed area of the triangle a, b, c.
+func cross(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+}
@@ -0,0 +1,128 @@
+package uvpad
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metric is the distance used to find the nearest opaque pixel.
+type Metric int
+
+const (
+	// MetricEuclidean is the straight-line distance.
+	MetricEuclidean Metric = iota
+	// MetricManhattan is the sum of the horizontal and vertical distances,
+	// which grows diamond shaped padding.
+	MetricManhattan
+	// MetricChebyshev is the larger of the horizontal and vertical
+	// distances, which grows square padding that matches how block
+	// compressors and mipmapping sample.
+	MetricChebyshev
+)
+
+var metricNames = []string{"euclidean", "manhattan", "chebyshev"}
+
+func (m Metric) String() string {
+	if int(m) < len(metricNames) {
+		return metricNames[m]
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+// ParseMetric returns the metric with the given name: euclidean, manhattan or
+// chebyshev.
+func ParseMetric(name string) (Metric, error) {
+	for i, n := range metricNames {
+		if n == name {
+			return Metric(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown metric %q, expected euclidean, manhattan or chebyshev", name)
+}
+
+// distance returns the length of an offset, squared for MetricEuclidean so
+// that it stays exact.
+func (m Metric) distance(dx, dy int) int64 {
+	x, y := int64(abs(dx)), int64(abs(dy))
+	switch m {
+	case MetricManhattan:
+		return x + y
+	case MetricChebyshev:
+		return max(x, y)
+	default:
+		return x*x + y*y
+	}
+}
+
+// limit returns the largest distance within radius, squared for
+// MetricEuclidean like distance, so that offsets are compared to the radius
+// exactly and without converting them. A zero radius is unlimited.
+func (m Metric) limit(radius float64) int64 {
+	if m == MetricEuclidean {
+		radius *= radius
+	}
+	if radius <= 0 || radius >= 1<<62 {
+		return math.MaxInt64
+	}
+	return int64(radius)
+}
+
+// within reports whether an offset is within limit, as returned by
+// Metric.limit.
+func (m Metric) within(dx, dy int, limit int64) bool {
+	return m.distance(dx, dy) <= limit
+}
+
+// separation returns the first x from which the distance to site u, with
+// column distance gu, is smaller than to site i < u, with column distance
+// gi, along a row (Meijster et al.).
+func (m Metric) separation(i, u int, gi, gu int64) int64 {
+	i64, u64 := int64(i), int64(u)
+	switch m {
+	case MetricManhattan:
+		if gu >= gi+u64-i64 {
+			return infinity
+		}
+		if gi > gu+u64-i64 {
+			return -infinity
+		}
+		return floorDiv(gu-gi+u64+i64, 2)
+	case MetricChebyshev:
+		if gi <= gu {
+			return max(i64+gu, floorDiv(i64+u64, 2))
+		}
+		return min(u64-gi, floorDiv(i64+u64, 2))
+	default:
+		return floorDiv(u64*u64-i64*i64+gu*gu-gi*gi, 2*(u64-i64))
+	}
+}
+
+// rowDistance is the distance from x to site i with column distance g.
+func (m Metric) rowDistance(x, i int, g int64) int64 {
+	d := int64(abs(x - i))
+	switch m {
+	case MetricManhattan:
+		return d + g
+	case MetricChebyshev:
+		return max(d, g)
+	default:
+		return d*d + g*g
+	}
+}
+
+const infinity = 1 << 62
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,69 @@
+package uvpad
+
+// erode clears the alpha of the seeds of b within distance pixels of a pixel
+// that isn't a seed, shrinking the opaque areas.
+func erode[T Sample](b *Buffer[T], distance float64, opts Options) {
+	seeds := seedMask(b, opts)
+	kept := shrink(seeds, b.Width, b.Height, distance, opts)
+	for idx, seed := range seeds {
+		if seed && !kept[idx] {
+			b.Pix[idx*4+3] = 0
+		}
+	}
+}
+
+// opening clears the alpha of the seeds of b that don't survive shrinking and
+// regrowing the seed mask by distance pixels: isolated texels and thin
+// features narrower than twice the distance.
+func opening[T Sample](b *Buffer[T], distance float64, opts Options) {
+	seeds := seedMask(b, opts)
+	opened := grow(shrink(seeds, b.Width, b.Height, distance, opts), b.Width, b.Height, distance, opts)
+	for idx, seed := range seeds {
+		if seed && !opened[idx] {
+			b.Pix[idx*4+3] = 0
+		}
+	}
+}
+
+// closing turns the pixels that growing and shrinking the seed mask of b by
+// distance pixels adds, such as pinholes and narrow gaps, into seeds with the
+// color of their nearest seed.
+func closing[T Sample](b *Buffer[T], distance float64, opts Options) {
+	seeds := seedMask(b, opts)
+	closed := shrink(grow(seeds, b.Width, b.Height, distance, opts), b.Width, b.Height, distance, opts)
+
+	transformOpts := opts
+	transformOpts.Progress = nil
+	nearest := distanceTransform(b.Width, b.Height, seeds, transformOpts)
+
+	opaque := opaqueValue[T]()
+	for idx, seed := range seeds {
+		if seed || !closed[idx] {
+			continue
+		}
+		copy(b.Pix[idx*4:idx*4+3], b.Pix[int(nearest[idx])*4:][:3])
+		b.Pix[idx*4+3] = opaque
+	}
+}
+
+// grow returns the pixels within distance of the mask.
+func grow(mask []bool, width, height int, distance float64, opts Options) []bool {
+	opts.Radius = distance
+	return withinRadius(width, height, mask, opts)
+}
+
+// shrink returns the pixels of the mask farther than distance from any pixel
+// outside it.
+func shrink(mask []bool, width, height int, distance float64, opts Options) []bool {
+	outside := make([]bool, len(mask))
+	for idx, v := range mask {
+		outside[idx] = !v
+	}
+	near := grow(outside, width, height, distance, opts)
+
+	shrunk := make([]bool, len(mask))
+	for idx, v := range mask {
+		shrunk[idx] = v && !near[idx]
+	}
+	return shrunk
+}
@@ -0,0 +1,453 @@
+package uvpad
+
+import "math"
+
+// The channels of an edge color.
+const (
+	edgeRed     = 1
+	edgeGreen   = 2
+	edgeBlue    = 4
+	edgeCyan    = edgeGreen | edgeBlue
+	edgeMagenta = edgeRed | edgeBlue
+	edgeWhite   = edgeRed | edgeGreen | edgeBlue
+)
+
+// cornerThreshold is the sine of the smallest turn between two edges that
+// makes a corner, about 8 degrees.
+var cornerThreshold = math.Sin(3.0)
+
+// simplifyTolerance is the distance in pixels within which the traced
+// boundary is simplified to straight edges, which removes the staircases of
+// diagonal edges.
+const simplifyTolerance = 0.5
+
+type vec struct{ x, y float64 }
+
+func (a vec) sub(b vec) vec          { return vec{a.x - b.x, a.y - b.y} }
+func (a vec) add(b vec) vec          { return vec{a.x + b.x, a.y + b.y} }
+func (a vec) scale(s float64) vec    { return vec{a.x * s, a.y * s} }
+func (a vec) dot(b vec) float64      { return a.x*b.x + a.y*b.y }
+func (a vec) cross(b vec) float64    { return a.x*b.y - a.y*b.x }
+func (a vec) length() float64        { return math.Hypot(a.x, a.y) }
+func (a vec) normalize() vec         { return a.scale(1 / a.length()) }
+func (a vec) distance(b vec) float64 { return a.sub(b).length() }
+
+// edge is a straight piece of the boundary, with the inside on its left
+// (positive cross product), and the channels it is assigned to.
+type edge struct {
+	a, b  vec
+	color int
+}
+
+// multiChannelSDF returns the multi-channel signed distance field of the
+// coverage of src: the boundary is traced and simplified to straight edges,
+// the edges are colored so that the two sides of every corner share one
+// channel, and every channel holds the distance to its nearest edge. The
+// median of the channels reconstructs the edges with sharp corners.
+func multiChannelSDF[T Sample](src *Buffer[T], opts Options) *Buffer[T] {
+	width, height := src.Width, src.Height
+	inside := seedMask(src, opts)
+
+	var edges []edge
+	for _, loop := range traceBoundary(inside, width, height) {
+		edges = append(edges, colorEdges(simplifyLoop(sharpenCorners(mergeCollinear(loop)), simplifyTolerance))...)
+	}
+	opts.progress(0.2)
+
+	spread := opts.Spread
+	if spread <= 0 {
+		spread = DefaultSpread
+	}
+	grid := newEdgeGrid(edges, width, height, spread+1)
+
+	out := NewBuffer[T](width, height)
+	forBands(height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				idx := y*width + x
+				channels, nearest := edgeDistances(vec{float64(x) + 0.5, float64(y) + 0.5}, grid, edges)
+
+				// Beyond the edges found, the sign comes from the coverage.
+				far := math.Inf(-1)
+				if inside[idx] {
+					far = math.Inf(1)
+				}
+				for c, d := range channels {
+					if math.IsNaN(d) {
+						channels[c] = far
+					}
+				}
+				if math.IsNaN(nearest) {
+					nearest = far
+				} else if !inside[idx] {
+					nearest = -nearest
+				}
+
+				// Where the median disagrees with the coverage the channels
+				// clash, so they fall back to the true distance.
+				if median := median3(channels[0], channels[1], channels[2]); (median > 0) != inside[idx] {
+					channels = [3]float64{nearest, nearest, nearest}
+				}
+
+				// Alpha, when selected, holds the true distance.
+				placeDistances(out, src, idx, [4]float64{channels[0], channels[1], channels[2], nearest}, opts)
+			}
+		}
+	})
+	opts.progress(1)
+	return out
+}
+
+// edgeDistances returns the signed pseudo-distance of p to the nearest edge
+// of every channel, positive inside, and the true distance to the nearest
+// edge. Distances are NaN without an edge within the reach of the grid.
+func edgeDistances(p vec, grid edgeGrid, edges []edge) (channels [3]float64, nearest float64) {
+	var best [3]struct {
+		distance, orthogonality float64
+		edge                    int
+	}
+	for c := range best {
+		best[c].distance, best[c].edge = math.Inf(1), -1
+	}
+	nearest = math.NaN()
+
+	grid.visit(p, func(i int) {
+		e := edges[i]
+		ab := e.b.sub(e.a)
+		t := max(0, min(1, p.sub(e.a).dot(ab)/ab.dot(ab)))
+		closest := e.a.add(ab.scale(t))
+		distance := p.distance(closest)
+
+		// Ties at a shared endpoint go to the edge the point is most
+		// perpendicular to.
+		orthogonality := 1.0
+		if (t == 0 || t == 1) && distance > 0 {
+			orthogonality = math.Abs(ab.normalize().cross(p.sub(closest).scale(1 / distance)))
+		}
+
+		if math.IsNaN(nearest) || distance < nearest {
+			nearest = distance
+		}
+		for c := range best {
+			if e.color&(1<<c) == 0 {
+				continue
+			}
+			b := &best[c]
+			if distance < b.distance-1e-9 || (distance < b.distance+1e-9 && orthogonality > b.orthogonality) {
+				b.distance, b.orthogonality, b.edge = distance, orthogonality, i
+			}
+		}
+	})
+
+	for c, b := range best {
+		if b.edge == -1 {
+			channels[c] = math.NaN()
+			continue
+		}
+		// The distance to the line through the edge, which extends the
+		// edges past the corners.
+		e := edges[b.edge]
+		channels[c] = e.b.sub(e.a).normalize().cross(p.sub(e.a))
+	}
+	return channels, nearest
+}
+
+func median3(a, b, c float64) float64 {
+	return max(min(a, b), min(max(a, b), c))
+}
+
+// traceBoundary returns the closed loops through the midpoints of the pixel
+// sides between inside and outside pixels, with the inside on the left.
+// Pixels beyond the image are outside. Diagonally touching inside pixels are
+// separate.
+func traceBoundary(inside []bool, width, height int) [][]vec {
+	isInside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < width && y < height && inside[y*width+x]
+	}
+
+	// The sides are directed between pixel corners, indexed y*(width+1)+x.
+	stride := width + 1
+	outgoing := map[int][]int{}
+	addSide := func(x0, y0, x1, y1 int) {
+		outgoing[y0*stride+x0] = append(outgoing[y0*stride+x0], y1*stride+x1)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !inside[y*width+x] {
+				continue
+			}
+			if !isInside(x, y-1) {
+				addSide(x, y, x+1, y)
+			}
+			if !isInside(x+1, y) {
+				addSide(x+1, y, x+1, y+1)
+			}
+			if !isInside(x, y+1) {
+				addSide(x+1, y+1, x, y+1)
+			}
+			if !isInside(x-1, y) {
+				addSide(x, y+1, x, y)
+			}
+		}
+	}
+
+	corner := func(v int) vec { return vec{float64(v % stride), float64(v / stride)} }
+
+	var loops [][]vec
+	for y := 0; y <= height; y++ {
+		for x := 0; x <= width; x++ {
+			start := y*stride + x
+			for len(outgoing[start]) > 0 {
+				var loop []vec
+				from := start
+				var direction vec
+				for len(loop) == 0 || from != start {
+					sides := outgoing[from]
+					// At a corner shared by diagonal pixels, turn toward the
+					// inside.
+					i := 0
+					if len(sides) > 1 && direction.cross(corner(sides[1]).sub(corner(from))) > 0 {
+						i = 1
+					}
+					to := sides[i]
+					outgoing[from] = append(sides[:i:i], sides[i+1:]...)
+
+					loop = append(loop, corner(from).add(corner(to)).scale(0.5))
+					direction = corner(to).sub(corner(from))
+					from = to
+				}
+				loops = append(loops, loop)
+			}
+		}
+	}
+	return loops
+}
+
+// mergeCollinear removes the points of a closed loop that continue the line
+// through their neighbours, so runs of pixel sides become single edges.
+func mergeCollinear(loop []vec) []vec {
+	n := len(loop)
+	var merged []vec
+	for i, p := range loop {
+		in, out := p.sub(loop[(i+n-1)%n]), loop[(i+1)%n].sub(p)
+		if in.cross(out) != 0 || in.dot(out) <= 0 {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// simplifyLoop reduces a closed loop to the points that keep it within
+// tolerance, with the Douglas-Peucker algorithm.
+func simplifyLoop(loop []vec, tolerance float64) []vec {
+	if len(loop) < 4 {
+		return loop
+	}
+
+	// Split the loop at the point farthest from the first.
+	far := 0
+	for i, p := range loop {
+		if p.distance(loop[0]) > loop[far].distance(loop[0]) {
+			far = i
+		}
+	}
+
+	keep := make([]bool, len(loop))
+	keep[0], keep[far] = true, true
+	closed := append(loop[:len(loop):len(loop)], loop[0])
+	simplifyRun(closed, 0, far, tolerance, keep)
+	simplifyRun(closed, far, len(loop), tolerance, keep)
+
+	var simplified []vec
+	for i, p := range loop {
+		if keep[i] {
+			simplified = append(simplified, p)
+		}
+	}
+	return simplified
+}
+
+func simplifyRun(points []vec, first, last int, tolerance float64, keep []bool) {
+	a, b := points[first], points[last]
+	farthest, distance := -1, tolerance
+	for i := first + 1; i < last; i++ {
+		if d := lineDistance(points[i], a, b); d > distance {
+			farthest, distance = i, d
+		}
+	}
+	if farthest == -1 {
+		return
+	}
+	keep[farthest] = true
+	simplifyRun(points, first, farthest, tolerance, keep)
+	simplifyRun(points, farthest, last, tolerance, keep)
+}
+
+// lineDistance returns the distance from p to the segment from a to b.
+func lineDistance(p, a, b vec) float64 {
+	ab := b.sub(a)
+	if ab.dot(ab) == 0 {
+		return p.distance(a)
+	}
+	t := max(0, min(1, p.sub(a).dot(ab)/ab.dot(ab)))
+	return p.distance(a.add(ab.scale(t)))
+}
+
+// sharpenCorners restores the corners that tracing through the midpoints of
+// the pixel sides cuts off: a short edge between two long straight ones is
+// replaced by the intersection of their lines.
+func sharpenCorners(loop []vec) []vec {
+	n := len(loop)
+	if n < 4 {
+		return loop
+	}
+	at := func(i int) vec { return loop[(i%n+n)%n] }
+
+	removed := make([]bool, n)
+	sharpened := append([]vec(nil), loop...)
+	for i := 0; i < n; i++ {
+		a, b, c, d := at(i-1), at(i), at(i+1), at(i+2)
+		if b.distance(c) >= 1.5 || a.distance(b) < 2 || c.distance(d) < 2 {
+			continue
+		}
+
+		ab, cd := b.sub(a), d.sub(c)
+		denominator := ab.cross(cd)
+		if denominator == 0 {
+			continue
+		}
+		corner := a.add(ab.scale(c.sub(a).cross(cd) / denominator))
+		if corner.distance(b.add(c).scale(0.5)) > 1.5 {
+			continue
+		}
+		sharpened[i] = corner
+		removed[(i+1)%n] = true
+	}
+
+	var out []vec
+	for i, p := range sharpened {
+		if !removed[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// colorEdges turns a closed loop into edges colored so that the edges on the
+// two sides of every corner share exactly one channel, following the simple
+// edge coloring of msdfgen. Loops without corners are white.
+func colorEdges(loop []vec) []edge {
+	n := len(loop)
+	if n < 2 {
+		return nil
+	}
+
+	var corners []int
+	for i := range loop {
+		previous := loop[i].sub(loop[(i+n-1)%n]).normalize()
+		next := loop[(i+1)%n].sub(loop[i]).normalize()
+		if previous.dot(next) <= 0 || math.Abs(previous.cross(next)) > cornerThreshold {
+			corners = append(corners, i)
+		}
+	}
+
+	if len(corners) == 1 && n < 3 {
+		// A teardrop needs three edges for its three colors.
+		var split []vec
+		for i, p := range loop {
+			q := loop[(i+1)%n]
+			split = append(split, p, p.add(q.sub(p).scale(1.0/3)), p.add(q.sub(p).scale(2.0/3)))
+		}
+		loop, n, corners = split, len(split), []int{corners[0] * 3}
+	}
+
+	edges := make([]edge, n)
+	for i := range loop {
+		edges[i] = edge{a: loop[i], b: loop[(i+1)%n], color: edgeWhite}
+	}
+
+	switch len(corners) {
+	case 0:
+	case 1:
+		// Three sections, of which the first and last meet at the corner.
+		colors := [3]int{edgeCyan, edgeWhite, edgeMagenta}
+		for i := range n {
+			edges[(corners[0]+i)%n].color = colors[i*3/n]
+		}
+	default:
+		color := edgeCyan
+		initial := color
+		corner := 0
+		for i := range n {
+			index := (corners[0] + i) % n
+			if corner+1 < len(corners) && corners[corner+1] == index {
+				corner++
+				banned := 0
+				if corner == len(corners)-1 {
+					banned = initial
+				}
+				color = switchColor(color, banned)
+			}
+			edges[index].color = color
+		}
+	}
+	return edges
+}
+
+// switchColor returns another two-channel color than color, and than banned
+// if they share a single channel.
+func switchColor(color, banned int) int {
+	if combined := color & banned; combined == edgeRed || combined == edgeGreen || combined == edgeBlue {
+		return combined ^ edgeWhite
+	}
+	shifted := color << 1
+	return (shifted | shifted>>3) & edgeWhite
+}
+
+// edgeGrid buckets the edges by the square cells they pass, so the edges
+// near a point are found without visiting all of them.
+type edgeGrid struct {
+	cellSize      float64
+	width, height int
+	cells         [][]int
+	reach         float64
+}
+
+func newEdgeGrid(edges []edge, width, height int, reach float64) edgeGrid {
+	cellSize := max(16, reach)
+	g := edgeGrid{
+		cellSize: cellSize,
+		width:    int(float64(width)/cellSize) + 1,
+		height:   int(float64(height)/cellSize) + 1,
+		reach:    reach,
+	}
+	g.cells = make([][]int, g.width*g.height)
+	for i, e := range edges {
+		x0, y0 := g.cell(min(e.a.x, e.b.x), min(e.a.y, e.b.y))
+		x1, y1 := g.cell(max(e.a.x, e.b.x), max(e.a.y, e.b.y))
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				g.cells[y*g.width+x] = append(g.cells[y*g.width+x], i)
+			}
+		}
+	}
+	return g
+}
+
+func (g edgeGrid) cell(x, y float64) (int, int) {
+	return max(0, min(g.width-1, int(x/g.cellSize))), max(0, min(g.height-1, int(y/g.cellSize)))
+}
+
+// visit calls fn with the index of every edge in the cells within the reach
+// of p, some of them more than once.
+func (g edgeGrid) visit(p vec, fn func(int)) {
+	x0, y0 := g.cell(p.x-g.reach, p.y-g.reach)
+	x1, y1 := g.cell(p.x+g.reach, p.y+g.reach)
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			for _, i := range g.cells[y*g.width+x] {
+				fn(i)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package uvpad
+
+import (
+	"fmt"
+	"math"
+)
+
+// NormalZ selects how the Z (blue) component of dilated normals is treated.
+type NormalZ int
+
+const (
+	// NormalZKeep renormalizes the dilated vector as is.
+	NormalZKeep NormalZ = iota
+	// NormalZPositive flips normals pointing into the surface before
+	// renormalizing, as tangent space normals always point out of it.
+	NormalZPositive
+	// NormalZReconstruct ignores the stored Z and derives it from X and Y,
+	// for two-channel normal maps such as BC5.
+	NormalZReconstruct
+)
+
+var normalZNames = []string{"keep", "positive", "reconstruct"}
+
+func (n NormalZ) String() string {
+	if int(n) < len(normalZNames) {
+		return normalZNames[n]
+	}
+	return fmt.Sprintf("NormalZ(%d)", int(n))
+}
+
+// ParseNormalZ returns the Z mode with the given name: keep, positive or
+// reconstruct.
+func ParseNormalZ(name string) (NormalZ, error) {
+	for i, n := range normalZNames {
+		if n == name {
+			return NormalZ(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown normal Z mode %q, expected keep, positive or reconstruct", name)
+}
+
+// renormalize makes the vectors of the pixels of dst that were filled, those
+// below seed in src and not transparent in dst, unit length again.
+func renormalize(dst, src *Buffer[float32], seed float32, mode NormalZ) {
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if src.Pix[i+3] >= seed || dst.Pix[i+3] == 0 {
+			continue
+		}
+
+		x, y, z := float64(dst.Pix[i]), float64(dst.Pix[i+1]), float64(dst.Pix[i+2])
+		switch mode {
+		case NormalZPositive:
+			z = math.Abs(z)
+		case NormalZReconstruct:
+			if length := math.Hypot(x, y); length > 1 {
+				x, y = x/length, y/length
+			}
+			z = math.Sqrt(max(0, 1-x*x-y*y))
+		}
+
+		length := math.Sqrt(x*x + y*y + z*z)
+		if length == 0 {
+			// Opposite normals cancelled out, fall back to the surface normal.
+			x, y, z, length = 0, 0, 1, 1
+		}
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = float32(x/length), float32(y/length), float32(z/length)
+	}
+}
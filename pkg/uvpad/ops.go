@@ -0,0 +1,281 @@
+package uvpad
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// OpKind is an operation of a pipeline.
+type OpKind int
+
+const (
+	// OpDilate runs the algorithm of the pipeline with the amount as radius,
+	// or Options.Radius without an amount.
+	OpDilate OpKind = iota
+	// OpErode shrinks the opaque areas by the amount, like Options.Erode.
+	OpErode
+	// OpOpen removes the seeds that don't survive an opening by the amount,
+	// like Options.Open.
+	OpOpen
+	// OpClose fills the gaps that a closing by the amount adds, like
+	// Options.Close.
+	OpClose
+	// OpBlur blurs the colors of the pixels filled by the last dilation over
+	// the amount in pixels, smoothing streaks while the seeds stay sharp.
+	OpBlur
+)
+
+var opNames = []string{"dilate", "erode", "open", "close", "blur"}
+
+func (k OpKind) String() string {
+	if int(k) < len(opNames) {
+		return opNames[k]
+	}
+	return fmt.Sprintf("OpKind(%d)", int(k))
+}
+
+// Op is one operation of a pipeline and its amount in pixels.
+type Op struct {
+	Kind   OpKind
+	Amount float64
+}
+
+func (o Op) String() string {
+	if o.Amount == 0 {
+		return o.Kind.String()
+	}
+	return o.Kind.String() + ":" + strconv.FormatFloat(o.Amount, 'g', -1, 64)
+}
+
+// ParseOps parses a comma separated pipeline of operations with their
+// amounts, such as erode:1,dilate:16,blur:2. The amount of dilate is
+// optional.
+func ParseOps(s string) ([]Op, error) {
+	var ops []Op
+	for _, field := range strings.Split(s, ",") {
+		name, amount, hasAmount := strings.Cut(strings.TrimSpace(field), ":")
+		kind := -1
+		for i, n := range opNames {
+			if n == name {
+				kind = i
+			}
+		}
+		if kind < 0 {
+			return nil, fmt.Errorf("unknown operation %q, expected dilate, erode, open, close or blur", name)
+		}
+
+		op := Op{Kind: OpKind(kind)}
+		if hasAmount {
+			v, err := strconv.ParseFloat(amount, 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("invalid amount %q of operation %s, expected a number of pixels", amount, name)
+			}
+			op.Amount = v
+		}
+		if op.Kind != OpDilate && op.Amount <= 0 {
+			return nil, fmt.Errorf("operation %s needs a positive amount, such as %s:1", name, name)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// Pipeline returns an algorithm that runs ops in order on the image, with alg
+// dilating, so several operations take a single decode and encode. The
+// options that change the coverage apply before the first operation, and
+// those that post-process the result after the last one.
+func Pipeline(alg Algorithm, ops []Op) Algorithm {
+	return pipeline{alg, ops}
+}
+
+type pipeline struct {
+	alg Algorithm
+	ops []Op
+}
+
+func (p pipeline) EstimateMemory(width, height, sampleSize int) int64 {
+	// A copy of the image per operation, and the blurred colors.
+	pixels := int64(width) * int64(height)
+	return estimate(p.alg, width, height, sampleSize) + pixels*(4*int64(sampleSize)+64)
+}
+
+func (p pipeline) Process(src Image, opts Options) Image {
+	if opts.Packed {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processPacked(src, opts, p.Process)
+		case *Buffer[uint16]:
+			return processPacked(src, opts, p.Process)
+		case *Buffer[float32]:
+			return processPacked(src, opts, p.Process)
+		}
+	}
+	if opts.Premultiplied {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processPremultiplied(src, opts, p.Process)
+		case *Buffer[uint16]:
+			return processPremultiplied(src, opts, p.Process)
+		case *Buffer[float32]:
+			return processPremultiplied(src, opts, p.Process)
+		}
+	}
+	if opts.changesCoverage() {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processCoverage(src, opts, p.Process)
+		case *Buffer[uint16]:
+			return processCoverage(src, opts, p.Process)
+		case *Buffer[float32]:
+			return processCoverage(src, opts, p.Process)
+		}
+	}
+
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return runOps(p, src, opts)
+	case *Buffer[uint16]:
+		return runOps(p, src, opts)
+	case *Buffer[float32]:
+		return runOps(p, src, opts)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+// runOps runs the operations of p on src and post-processes the result.
+func runOps[T Sample](p pipeline, src *Buffer[T], opts Options) *Buffer[T] {
+	// The post-processing applies once, to the result of the pipeline.
+	stepOpts := opts
+	stepOpts.FadeDistance, stepOpts.Feather, stepOpts.Background = 0, 0, Background{}
+	stepOpts.Composite, stepOpts.KeepAlpha, stepOpts.Channels = false, false, 0
+
+	// Every operation replaces out, whose pixels go back to the pools unless
+	// they are those of src.
+	out := src
+	replace := func(next *Buffer[T]) {
+		if out != src {
+			out.release()
+		}
+		out = next
+	}
+	// The morphological operations work in place on a copy of src.
+	owned := func() *Buffer[T] {
+		if out == src {
+			out = src.Clone()
+		}
+		return out
+	}
+	var filled []bool
+	for i, op := range p.ops {
+		stepOpts.Progress = func(fraction float64) {
+			opts.progress((float64(i) + fraction) / float64(len(p.ops)))
+		}
+
+		switch op.Kind {
+		case OpDilate:
+			dilateOpts := stepOpts
+			if op.Amount > 0 {
+				dilateOpts.Radius = op.Amount
+			}
+			seeds := seedMask(out, stepOpts)
+			replace(p.alg.Process(out, dilateOpts).(*Buffer[T]))
+			filled = make([]bool, len(seeds))
+			for idx, seed := range seeds {
+				filled[idx] = !seed
+			}
+		case OpErode:
+			erode(owned(), op.Amount, stepOpts)
+		case OpOpen:
+			opening(owned(), op.Amount, stepOpts)
+		case OpClose:
+			closing(owned(), op.Amount, stepOpts)
+		case OpBlur:
+			if filled != nil {
+				replace(blurFilled(out, filled, op.Amount, stepOpts))
+			}
+		}
+		stepOpts.progress(1)
+	}
+
+	if out == src {
+		out = src.Clone()
+	}
+	finish(out, src, opts)
+	return out
+}
+
+// blurFilled returns a copy of b whose filled pixels take the average color
+// of the pixels with alpha within radius pixels, weighted by a gaussian whose
+// standard deviation is a third of the radius and by their alpha.
+func blurFilled[T Sample](b *Buffer[T], filled []bool, radius float64, opts Options) *Buffer[T] {
+	width, height := b.Width, b.Height
+	sigma := radius / 3
+	taps := int(math.Ceil(radius))
+	kernel := make([]float64, 2*taps+1)
+	for i := range kernel {
+		d := float64(i - taps)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+
+	// Colors premultiplied by alpha, so transparent pixels don't contribute.
+	weighted := make([]float64, len(b.Pix))
+	for i := 0; i < len(b.Pix); i += 4 {
+		a := normalize(b.Pix[i+3])
+		for c := 0; c < 3; c++ {
+			weighted[i+c] = normalize(b.Pix[i+c]) * a
+		}
+		weighted[i+3] = a
+	}
+
+	// Horizontal, then vertical pass.
+	horizontal := make([]float64, len(weighted))
+	forBands(height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				sum := horizontal[(y*width+x)*4:][:4]
+				for i, k := range kernel {
+					nx, ok := opts.Edge.resolve(x+i-taps, width)
+					if !ok {
+						continue
+					}
+					for c, v := range weighted[(y*width+nx)*4:][:4] {
+						sum[c] += k * v
+					}
+				}
+			}
+		}
+	})
+
+	out := b.Clone()
+	forBands(height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				idx := y*width + x
+				if !filled[idx] || b.Pix[idx*4+3] == 0 {
+					continue
+				}
+
+				var sum [4]float64
+				for i, k := range kernel {
+					ny, ok := opts.Edge.resolve(y+i-taps, height)
+					if !ok {
+						continue
+					}
+					for c, v := range horizontal[(ny*width+x)*4:][:4] {
+						sum[c] += k * v
+					}
+				}
+				if sum[3] == 0 {
+					continue
+				}
+				for c := 0; c < 3; c++ {
+					out.Pix[idx*4+c] = denormalize[T](sum[c] / sum[3])
+				}
+			}
+		}
+	})
+	return out
+}
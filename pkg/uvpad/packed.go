@@ -0,0 +1,50 @@
+package uvpad
+
+import "image"
+
+// processPacked dilates the color channels of a channel-packed texture
+// independently: every channel with its own mask in opts.ChannelMasks in a
+// run seeded by that mask, the others together with the alpha channel in a
+// run seeded as usual.
+func processPacked[T Sample](src *Buffer[T], opts Options, process func(Image, Options) Image) *Buffer[T] {
+	masks := opts.ChannelMasks
+	opts.Packed, opts.ChannelMasks = false, [3]image.Image{}
+	// The channels hold data rather than colors.
+	opts.ColorSpace = ColorSpaceSRGB
+
+	runs := 1
+	for _, mask := range masks {
+		if mask != nil {
+			runs++
+		}
+	}
+	progress := opts.Progress
+	runOpts := func(run int) Options {
+		o := opts
+		if progress != nil {
+			o.Progress = func(fraction float64) {
+				progress((float64(run) + fraction) / float64(runs))
+			}
+		}
+		return o
+	}
+
+	out := process(src, runOpts(0)).(*Buffer[T])
+	run := 1
+	for c, mask := range masks {
+		if mask == nil {
+			continue
+		}
+		o := runOpts(run)
+		o.Mask = mask
+		channel := process(src, o).(*Buffer[T])
+		for i := c; i < len(out.Pix); i += 4 {
+			out.Pix[i] = channel.Pix[i]
+		}
+		if channel != src {
+			Release(channel)
+		}
+		run++
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package uvpad
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// maxPooledClass is the size class of the largest pooled slices, 1<<22
+// elements, the samples of a 1024×1024 image. Rounding larger ones up to a
+// power of two would waste more memory than their allocation costs.
+const maxPooledClass = 22
+
+// slicePool recycles slices by size class, the next power of two of their
+// length, so that a batch of small images reuses the same few buffers and
+// the garbage collector has little to do between them. The pools are shared
+// by every goroutine, so concurrent jobs hold about one set of buffers each
+// rather than one per image processed.
+type slicePool[T any] struct {
+	classes [maxPooledClass + 1]sync.Pool
+}
+
+// get returns a slice of length n. Its contents are undefined.
+func (p *slicePool[T]) get(n int) []T {
+	class := bits.Len(uint(n - 1))
+	if n == 0 || class > maxPooledClass {
+		return make([]T, n)
+	}
+	if s, ok := p.classes[class].Get().(*[]T); ok {
+		return (*s)[:n]
+	}
+	return make([]T, n, 1<<class)
+}
+
+// put returns s to the pool. Slices that didn't come from get are dropped.
+func (p *slicePool[T]) put(s []T) {
+	c := cap(s)
+	if c == 0 || c&(c-1) != 0 || c > 1<<maxPooledClass {
+		return
+	}
+	s = s[:0]
+	p.classes[bits.Len(uint(c-1))].Put(&s)
+}
+
+var (
+	maskPool slicePool[bool]
+	seedPool slicePool[int32]
+	// distancePool holds the column distances of the distance transform.
+	distancePool slicePool[int32]
+
+	uint8Pool   slicePool[uint8]
+	uint16Pool  slicePool[uint16]
+	float32Pool slicePool[float32]
+)
+
+// pixelPool returns the pool of the samples of T.
+func pixelPool[T Sample]() *slicePool[T] {
+	var p any
+	var v T
+	switch any(v).(type) {
+	case uint8:
+		p = &uint8Pool
+	case uint16:
+		p = &uint16Pool
+	default:
+		p = &float32Pool
+	}
+	return p.(*slicePool[T])
+}
+
+// Release returns the pixels of img, a buffer created by this package, to
+// the pool that new buffers are taken from. img must not be used afterwards.
+// Releasing is optional and only pays off when processing many images in a
+// row; images other than buffers are ignored, and so are the pixels a buffer
+// shares with an image from FromImage or Image.
+func Release(img Image) {
+	switch b := img.(type) {
+	case *Buffer[uint8]:
+		b.release()
+	case *Buffer[uint16]:
+		b.release()
+	case *Buffer[float32]:
+		b.release()
+	}
+}
+
+func (b *Buffer[T]) release() {
+	if !b.borrowed {
+		pixelPool[T]().put(b.Pix)
+	}
+	b.Pix, b.Width, b.Height = nil, 0, 0
+}
@@ -0,0 +1,29 @@
+package uvpad
+
+// processPremultiplied runs process on the straight alpha decoding of a buffer
+// with premultiplied colors, and premultiplies the result again.
+func processPremultiplied[T Sample](src *Buffer[T], opts Options, process func(Image, Options) Image) *Buffer[T] {
+	straight := src.Clone()
+	for i := 0; i < len(straight.Pix); i += 4 {
+		alpha := normalize(straight.Pix[i+3])
+		if alpha == 0 {
+			continue
+		}
+		for c := i; c < i+3; c++ {
+			straight.Pix[c] = denormalize[T](normalize(straight.Pix[c]) / alpha)
+		}
+	}
+
+	opts.Premultiplied = false
+	out := process(straight, opts).(*Buffer[T])
+	if out != straight {
+		straight.release()
+	}
+	for i := 0; i < len(out.Pix); i += 4 {
+		alpha := normalize(out.Pix[i+3])
+		for c := i; c < i+3; c++ {
+			out.Pix[c] = denormalize[T](normalize(out.Pix[c]) * alpha)
+		}
+	}
+	return out
+}
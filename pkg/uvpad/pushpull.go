@@ -0,0 +1,204 @@
+package uvpad
+
+import "math"
+
+func init() {
+	Register("pushpull", generic{
+		processPushPull[uint8], processPushPull[uint16], processPushPull[float32],
+		func(int64) int64 {
+			// The pyramid of weighted colors, 4/3 of the image at 16 bytes
+			// per pixel.
+			return 22
+		},
+	})
+}
+
+// level is one level of the push-pull pyramid: colors premultiplied by their
+// weight, which is the coverage of the level's pixel by opaque pixels.
+type level struct {
+	width, height int
+	pix           []float32
+}
+
+func (l level) at(x, y int) []float32 {
+	return l.pix[(y*l.width+x)*4:][:4]
+}
+
+// processPushPull fills the transparent areas with the push-pull (pull-push)
+// algorithm of Gortler et al.: the opaque pixels are averaged down a pyramid
+// of halving resolutions, then every level fills its gaps with the bilinear
+// upsampling of the next coarser one. Large holes get a smooth, low frequency
+// fill instead of streaks.
+func processPushPull[T Sample](input *Buffer[T], opts Options) *Buffer[T] {
+	opaqueMask := seedMask(input, opts)
+	levels := pyramid(seedLevel(input, opaqueMask), opts)
+	opts.progress(0.5)
+
+	for i := len(levels) - 2; i >= 0; i-- {
+		pull(levels[i], levels[i+1], opts)
+		opts.progress(0.5 + 0.5*float64(len(levels)-1-i)/float64(len(levels)-1))
+	}
+
+	output := fillLevel(input, opaqueMask, levels[0], opts)
+	releaseLevels(levels)
+	maskPool.put(opaqueMask)
+	opts.progress(1)
+	return output
+}
+
+// releaseLevels returns the pixels of a pyramid to the pool.
+func releaseLevels(levels []level) {
+	for _, l := range levels {
+		float32Pool.put(l.pix)
+	}
+}
+
+// seedLevel returns the base of a pyramid: the colors of the seeds with a
+// weight of 1, and 0 elsewhere.
+func seedLevel[T Sample](input *Buffer[T], opaqueMask []bool) level {
+	base := level{input.Width, input.Height, float32Pool.get(input.Width * input.Height * 4)}
+	clear(base.pix)
+	for idx, opaque := range opaqueMask {
+		if opaque {
+			pixel := base.pix[idx*4:][:4]
+			for c := 0; c < 3; c++ {
+				pixel[c] = float32(normalize(input.Pix[idx*4+c]))
+			}
+			pixel[3] = 1
+		}
+	}
+	return base
+}
+
+// pyramid pushes base down to a single pixel, returning every level from
+// base to the coarsest.
+func pyramid(base level, opts Options) []level {
+	levels := []level{base}
+	for base.width > 1 || base.height > 1 {
+		base = push(base, opts)
+		levels = append(levels, base)
+	}
+	return levels
+}
+
+// fillLevel returns a copy of input where every pixel outside the seed mask
+// takes its color from a filled base level, or is cleared if it has no
+// weight or is beyond the radius.
+func fillLevel[T Sample](input *Buffer[T], opaqueMask []bool, base level, opts Options) *Buffer[T] {
+	width, height := input.Width, input.Height
+
+	var within []bool
+	if opts.Radius > 0 {
+		within = withinRadius(width, height, opaqueMask, opts)
+	}
+
+	output := input.Clone()
+	opaque := opaqueValue[T]()
+	for idx, isOpaque := range opaqueMask {
+		if isOpaque {
+			continue
+		}
+
+		pixel := output.Pix[idx*4 : idx*4+4]
+		weighted := base.pix[idx*4:][:4]
+		if weighted[3] == 0 || (within != nil && !within[idx]) {
+			clear(pixel)
+			continue
+		}
+		for c := 0; c < 3; c++ {
+			pixel[c] = denormalize[T](float64(weighted[c] / weighted[3]))
+		}
+		pixel[3] = opaque
+	}
+	return output
+}
+
+// push returns the next coarser level, each pixel summing the 2x2 pixels it
+// covers. Weights are capped at 1, so that fully covered pixels average their
+// colors.
+func push(fine level, opts Options) level {
+	coarse := level{(fine.width + 1) / 2, (fine.height + 1) / 2, nil}
+	coarse.pix = float32Pool.get(coarse.width * coarse.height * 4)
+	clear(coarse.pix)
+
+	forBands(coarse.height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < coarse.width; x++ {
+				pixel := coarse.at(x, y)
+				for fy := 2 * y; fy < min(2*y+2, fine.height); fy++ {
+					for fx := 2 * x; fx < min(2*x+2, fine.width); fx++ {
+						for c, v := range fine.at(fx, fy) {
+							pixel[c] += v
+						}
+					}
+				}
+				if pixel[3] > 1 {
+					scale := 1 / pixel[3]
+					for c := range pixel {
+						pixel[c] *= scale
+					}
+				}
+			}
+		}
+	})
+	return coarse
+}
+
+// pull fills the missing weight of every pixel of fine with the bilinear
+// upsampling of coarse.
+func pull(fine, coarse level, opts Options) {
+	sample := func(v, size int) int {
+		if v, ok := opts.Edge.resolve(v, size); ok {
+			return v
+		}
+		return max(0, min(v, size-1))
+	}
+
+	forBands(fine.height, opts, func(start, end int) {
+		for y := start; y < end; y++ {
+			cy := (float64(y)+0.5)/2 - 0.5
+			y0 := int(math.Floor(cy))
+			ty := float32(cy - float64(y0))
+
+			for x := 0; x < fine.width; x++ {
+				pixel := fine.at(x, y)
+				missing := 1 - pixel[3]
+				if missing <= 0 {
+					continue
+				}
+
+				cx := (float64(x)+0.5)/2 - 0.5
+				x0 := int(math.Floor(cx))
+				tx := float32(cx - float64(x0))
+
+				p00 := coarse.at(sample(x0, coarse.width), sample(y0, coarse.height))
+				p10 := coarse.at(sample(x0+1, coarse.width), sample(y0, coarse.height))
+				p01 := coarse.at(sample(x0, coarse.width), sample(y0+1, coarse.height))
+				p11 := coarse.at(sample(x0+1, coarse.width), sample(y0+1, coarse.height))
+				for c := range pixel {
+					top := p00[c] + (p10[c]-p00[c])*tx
+					bottom := p01[c] + (p11[c]-p01[c])*tx
+					pixel[c] += missing * (top + (bottom-top)*ty)
+				}
+			}
+		}
+	})
+}
+
+// withinRadius marks the pixels within opts.Radius of a seed.
+func withinRadius(width, height int, opaqueMask []bool, opts Options) []bool {
+	transformOpts := opts
+	transformOpts.Progress = nil
+	nearest := distanceTransform(width, height, opaqueMask, transformOpts)
+
+	within := make([]bool, len(nearest))
+	limit := opts.Metric.limit(opts.Radius)
+	for idx, seed := range nearest {
+		if seed != noSeed {
+			dx, dy := seedOffset(idx%width, idx/width, seed, width, height, opts.Edge)
+			within[idx] = opts.Metric.within(dx, dy, limit)
+		}
+	}
+	seedPool.put(nearest)
+	return within
+}
@@ -0,0 +1,108 @@
+package uvpad
+
+import "math"
+
+// MeshReport describes how the UV layout of a mesh uses a texture.
+type MeshReport struct {
+	Islands []IslandReport
+	// Coverage is the fraction of the texels covered by the layout.
+	Coverage float64
+	// MinGap is the smallest number of texels between two islands, 0 when
+	// islands touch or overlap and +Inf with fewer than two islands.
+	MinGap float64
+}
+
+// IslandReport describes a UV island of a mesh.
+type IslandReport struct {
+	Triangles int
+	// Texels is the area of the island in texels.
+	Texels float64
+	// Area is the surface area of the island in model units, 0 without 3D
+	// vertices.
+	Area float64
+	// Density is the number of texels per model unit, the square root of
+	// texels per unit of area, 0 without 3D vertices.
+	Density float64
+}
+
+// Report measures the texel density of every UV island of the mesh in a
+// width by height texture, the coverage of the texture and the smallest gap
+// between islands, which limits the padding that can be added without one
+// island bleeding into another.
+func (m *Mesh) Report(width, height int) MeshReport {
+	triangleIslands, count := m.triangleIslands()
+	r := MeshReport{Islands: make([]IslandReport, count), MinGap: math.Inf(1)}
+
+	for t, island := range triangleIslands {
+		corners := m.Triangles[t]
+		a, b, c := m.pixel(corners[0], width, height), m.pixel(corners[1], width, height), m.pixel(corners[2], width, height)
+		report := &r.Islands[island]
+		report.Triangles++
+		report.Texels += math.Abs(cross(a, b, c)) / 2
+		if t < len(m.Positions) {
+			report.Area += m.surfaceArea(m.Positions[t])
+		}
+	}
+	for i := range r.Islands {
+		if r.Islands[i].Area > 0 {
+			r.Islands[i].Density = math.Sqrt(r.Islands[i].Texels / r.Islands[i].Area)
+		}
+	}
+
+	islands := m.Islands(width, height)
+	covered := make([]bool, len(islands.Islands))
+	for idx, island := range islands.Islands {
+		covered[idx] = island >= 0
+		if covered[idx] {
+			r.Coverage++
+		}
+	}
+	r.Coverage /= float64(width * height)
+
+	// The nearest islands meet where neighbouring pixels of the Voronoi
+	// diagram of the covered texels have seeds of different islands, whose
+	// distance is the gap.
+	nearest := distanceTransform(width, height, covered, Options{})
+	for y := range height {
+		for x := range width {
+			p := nearest[y*width+x]
+			if p == noSeed {
+				continue
+			}
+			for _, n := range [][2]int{{x + 1, y}, {x, y + 1}} {
+				if n[0] >= width || n[1] >= height {
+					continue
+				}
+				q := nearest[n[1]*width+n[0]]
+				if q == noSeed || islands.Islands[p] == islands.Islands[q] {
+					continue
+				}
+				gap := math.Hypot(float64(int(p)%width-int(q)%width), float64(int(p)/width-int(q)/width)) - 1
+				r.MinGap = min(r.MinGap, max(0, gap))
+			}
+		}
+	}
+	return r
+}
+
+// surfaceArea returns the area of the triangle between the 3D vertices of
+// positions, 0 if any of them is unknown.
+func (m *Mesh) surfaceArea(positions [3]int) float64 {
+	var v [3][3]float64
+	for i, p := range positions {
+		if p < 0 || p >= len(m.Vertices) {
+			return 0
+		}
+		v[i] = m.Vertices[p]
+	}
+	var e1, e2 [3]float64
+	for i := range 3 {
+		e1[i], e2[i] = v[1][i]-v[0][i], v[2][i]-v[0][i]
+	}
+	n := [3]float64{
+		e1[1]*e2[2] - e1[2]*e2[1],
+		e1[2]*e2[0] - e1[0]*e2[2],
+		e1[0]*e2[1] - e1[1]*e2[0],
+	}
+	return math.Sqrt(n[0]*n[0]+n[1]*n[1]+n[2]*n[2]) / 2
+}
@@ -0,0 +1,145 @@
+package uvpad
+
+import "fmt"
+
+// DefaultSpread is the distance in pixels covered by a signed distance field
+// when Options.Spread is zero.
+const DefaultSpread = 8
+
+// SignedDistanceField returns the signed distance field of the coverage of
+// src instead of dilating it, for rendering text and UI from the same masks:
+// 0.5 on the edge of the opaque areas, rising to 1 Options.Spread pixels
+// inside them and falling to 0 as far outside, in the color channels of an
+// opaque image. With Options.MSDF the channels hold a multi-channel distance
+// field instead. Options.SDFSign and Options.SDFChannels change the encoding
+// and the channels that hold it. The options that change the coverage apply
+// as for dilation.
+func SignedDistanceField(src Image, opts Options) Image {
+	if opts.changesCoverage() {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return processCoverage(src, opts, SignedDistanceField)
+		case *Buffer[uint16]:
+			return processCoverage(src, opts, SignedDistanceField)
+		case *Buffer[float32]:
+			return processCoverage(src, opts, SignedDistanceField)
+		}
+	}
+
+	if opts.MSDF {
+		switch src := src.(type) {
+		case *Buffer[uint8]:
+			return multiChannelSDF(src, opts)
+		case *Buffer[uint16]:
+			return multiChannelSDF(src, opts)
+		case *Buffer[float32]:
+			return multiChannelSDF(src, opts)
+		}
+	}
+
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return signedDistanceField(src, opts)
+	case *Buffer[uint16]:
+		return signedDistanceField(src, opts)
+	case *Buffer[float32]:
+		return signedDistanceField(src, opts)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+func signedDistanceField[T Sample](src *Buffer[T], opts Options) *Buffer[T] {
+	width, height := src.Width, src.Height
+	inside := seedMask(src, opts)
+	outside := make([]bool, len(inside))
+	for idx, v := range inside {
+		outside[idx] = !v
+	}
+
+	transformOpts := opts
+	transformOpts.Progress = func(fraction float64) { opts.progress(fraction / 2) }
+	toInside := nearestDistances(width, height, distanceTransform(width, height, inside, transformOpts), opts)
+	transformOpts.Progress = func(fraction float64) { opts.progress(0.5 + fraction/2) }
+	toOutside := nearestDistances(width, height, distanceTransform(width, height, outside, transformOpts), opts)
+
+	out := NewBuffer[T](width, height)
+	for idx, isInside := range inside {
+		// The edge lies half a pixel between the inside and outside pixels.
+		var d float64
+		if isInside {
+			d = toOutside[idx] - 0.5
+		} else {
+			d = -(toInside[idx] - 0.5)
+		}
+		placeDistances(out, src, idx, [4]float64{d, d, d, d}, opts)
+	}
+	return out
+}
+
+// Sign is the side of the edge whose distances a signed distance field
+// encodes above 0.5.
+type Sign int
+
+const (
+	// InsidePositive encodes the opaque areas above 0.5.
+	InsidePositive Sign = iota
+	// OutsidePositive encodes the transparent areas above 0.5, for shaders
+	// that expect distances to the shape.
+	OutsidePositive
+)
+
+var signNames = []string{"inside", "outside"}
+
+func (s Sign) String() string {
+	if int(s) < len(signNames) {
+		return signNames[s]
+	}
+	return fmt.Sprintf("Sign(%d)", int(s))
+}
+
+// ParseSign returns the sign convention with the given name: inside or
+// outside, the side that is positive.
+func ParseSign(name string) (Sign, error) {
+	for i, n := range signNames {
+		if n == name {
+			return Sign(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sign %q, expected inside or outside", name)
+}
+
+// encodeDistance maps a signed distance in pixels, positive inside, to 0.5 on
+// the edge and 0 or 1 Options.Spread pixels away. Float samples keep the
+// values beyond.
+func (o Options) encodeDistance(d float64) float64 {
+	if o.SDFSign == OutsidePositive {
+		d = -d
+	}
+	spread := o.Spread
+	if spread <= 0 {
+		spread = DefaultSpread
+	}
+	return 0.5 + d/(2*spread)
+}
+
+// placeDistances writes the pixel at idx of a signed distance field: the
+// distances of the channels in Options.SDFChannels, RGB if zero, encoded,
+// the other color channels copied from src and alpha otherwise opaque.
+func placeDistances[T Sample](out, src *Buffer[T], idx int, distances [4]float64, opts Options) {
+	channels := opts.SDFChannels
+	if channels == 0 {
+		channels = ChannelsRGB
+	}
+	pixel := out.Pix[idx*4 : idx*4+4]
+	for c, d := range distances {
+		switch {
+		case channels&(1<<c) != 0:
+			pixel[c] = denormalize[T](opts.encodeDistance(d))
+		case c == 3:
+			pixel[c] = opaqueValue[T]()
+		default:
+			pixel[c] = src.Pix[idx*4+c]
+		}
+	}
+}
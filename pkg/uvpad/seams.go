@@ -0,0 +1,134 @@
+package uvpad
+
+import "math"
+
+// seamSide is one side of a UV seam: the texture coordinates of the ends of
+// the shared 3D edge, in the order of their positions, and of the opposite
+// corner of the triangle, which lies inside the chart.
+type seamSide struct {
+	a, b, c [2]float64
+}
+
+// seams returns the UV seams of the mesh, the 3D edges shared by two
+// triangles at different texture coordinates.
+func (m *Mesh) seams() [][2]seamSide {
+	if len(m.Positions) != len(m.Triangles) {
+		return nil
+	}
+
+	edges := map[[2]int][]seamSide{}
+	var keys [][2]int
+	for t, corners := range m.Triangles {
+		positions := m.Positions[t]
+		for i := range 3 {
+			j, k := (i+1)%3, (i+2)%3
+			pa, pb := positions[i], positions[j]
+			if pa < 0 || pb < 0 || pa == pb {
+				continue
+			}
+			side := seamSide{m.UV[corners[i]], m.UV[corners[j]], m.UV[corners[k]]}
+			if pa > pb {
+				pa, pb = pb, pa
+				side.a, side.b = side.b, side.a
+			}
+			key := [2]int{pa, pb}
+			if _, ok := edges[key]; !ok {
+				keys = append(keys, key)
+			}
+			edges[key] = append(edges[key], side)
+		}
+	}
+
+	var seams [][2]seamSide
+	for _, key := range keys {
+		// Only manifold edges have a single other side.
+		sides := edges[key]
+		if len(sides) == 2 && (sides[0].a != sides[1].a || sides[0].b != sides[1].b) {
+			seams = append(seams, [2]seamSide{sides[0], sides[1]})
+		}
+	}
+	return seams
+}
+
+// StitchSeams returns a copy of src in which the texels along the UV seams
+// of mesh, where a 3D edge is split between two charts, take the average of
+// the colors on both sides, so that the dilation of both charts continues
+// the same colors and no seam shows. Only the seeds by the coverage of opts
+// are changed.
+func StitchSeams(src Image, mesh *Mesh, opts Options) Image {
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return stitchSeams(src, mesh, opts)
+	case *Buffer[uint16]:
+		return stitchSeams(src, mesh, opts)
+	case *Buffer[float32]:
+		return stitchSeams(src, mesh, opts)
+	default:
+		panic("uvpad: unsupported image type")
+	}
+}
+
+func stitchSeams[T Sample](src *Buffer[T], mesh *Mesh, opts Options) *Buffer[T] {
+	width, height := src.Width, src.Height
+	covered := src
+	if opts.changesCoverage() {
+		covered, opts = applyCoverage(src, opts)
+	}
+	seeds := seedMask(covered, opts)
+
+	// texel returns the seed texel half a pixel inside the chart from the
+	// point t along the edge of side, or -1.
+	texel := func(side seamSide, t float64) int {
+		toPixels := func(uv [2]float64) [2]float64 {
+			return [2]float64{uv[0] * float64(width), (1 - uv[1]) * float64(height)}
+		}
+		a, b, c := toPixels(side.a), toPixels(side.b), toPixels(side.c)
+		p := [2]float64{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+		n := [2]float64{a[1] - b[1], b[0] - a[0]}
+		if length := math.Hypot(n[0], n[1]); length > 0 {
+			n[0], n[1] = n[0]/length, n[1]/length
+		}
+		if (c[0]-a[0])*n[0]+(c[1]-a[1])*n[1] < 0 {
+			n[0], n[1] = -n[0], -n[1]
+		}
+		x, y := int(math.Floor(p[0]+n[0]/2)), int(math.Floor(p[1]+n[1]/2))
+		if x < 0 || y < 0 || x >= width || y >= height || !seeds[y*width+x] {
+			return -1
+		}
+		return y*width + x
+	}
+
+	// Texels on several seams, or sampled several times, average all of
+	// their targets.
+	sums := map[int][4]float64{}
+	for _, seam := range mesh.seams() {
+		var length float64
+		for _, side := range seam {
+			length = max(length, math.Hypot((side.b[0]-side.a[0])*float64(width), (side.b[1]-side.a[1])*float64(height)))
+		}
+		samples := int(math.Ceil(length*2)) + 1
+		for k := range samples {
+			t := (float64(k) + 0.5) / float64(samples)
+			i, j := texel(seam[0], t), texel(seam[1], t)
+			if i < 0 || j < 0 || i == j {
+				continue
+			}
+			for _, idx := range []int{i, j} {
+				sum := sums[idx]
+				for c := range 3 {
+					sum[c] += (normalize(src.Pix[i*4+c]) + normalize(src.Pix[j*4+c])) / 2
+				}
+				sum[3]++
+				sums[idx] = sum
+			}
+		}
+	}
+
+	out := src.Clone()
+	for idx, sum := range sums {
+		for c := range 3 {
+			out.Pix[idx*4+c] = denormalize[T](sum[c] / sum[3])
+		}
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package uvpad
+
+import "fmt"
+
+// Seed is the source of the coverage that decides which pixels are seeds.
+type Seed int
+
+const (
+	// SeedAlpha seeds the pixels by their alpha.
+	SeedAlpha Seed = iota
+	// SeedLuminance seeds the pixels whose luminance is above
+	// Options.Threshold, for single channel bakes such as ambient occlusion
+	// or curvature where black means not rasterized.
+	SeedLuminance
+)
+
+var seedNames = []string{"alpha", "luminance"}
+
+func (s Seed) String() string {
+	if int(s) < len(seedNames) {
+		return seedNames[s]
+	}
+	return fmt.Sprintf("Seed(%d)", int(s))
+}
+
+// ParseSeed returns the seed source with the given name: alpha or luminance.
+func ParseSeed(name string) (Seed, error) {
+	for i, n := range seedNames {
+		if n == name {
+			return Seed(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown seed %q, expected alpha or luminance", name)
+}
+
+// luminanceCoverage makes the pixels of b whose luminance is above threshold
+// opaque, and every other pixel transparent.
+func luminanceCoverage[T Sample](b *Buffer[T], threshold float64) {
+	opaque := opaqueValue[T]()
+	for i := 0; i < len(b.Pix); i += 4 {
+		luminance := 0.299*normalize(b.Pix[i]) + 0.587*normalize(b.Pix[i+1]) + 0.114*normalize(b.Pix[i+2])
+		if luminance > threshold {
+			b.Pix[i+3] = opaque
+		} else {
+			b.Pix[i+3] = 0
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package uvpad
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// tiled reports whether a width by height image is dilated in tiles.
+func (o Options) tiled(width, height int) bool {
+	return o.Tile > 0 && o.Radius > 0 && (width > o.Tile || height > o.Tile)
+}
+
+// tileMargin is the number of pixels around every tile that are dilated with
+// it: those within the radius of its pixels.
+func (o Options) tileMargin() int {
+	return int(math.Ceil(o.Radius)) + 1
+}
+
+// ProcessTiles dilates src into dst, a buffer of the same size and sample
+// type, with alg one tile of opts.Tile pixels at a time with a margin of the
+// radius around it, so that only a tile is held in memory besides src and
+// dst, which can be memory-mapped files larger than the memory. Every tile is
+// processed as a whole image, post-processing included. Without a Tile or a
+// Radius src is processed at once. The masks and islands of Options cover
+// the whole image, so they aren't supported.
+func ProcessTiles(alg Algorithm, src, dst Image, opts Options) error {
+	if opts.Mask != nil || opts.Islands != nil || opts.ChannelMasks != [3]image.Image{} {
+		return errors.New("uvpad: masks and islands can't be processed in tiles")
+	}
+	width, height := src.Size()
+	if w, h := dst.Size(); w != width || h != height {
+		return errors.New("uvpad: the source and destination of tiles differ in size")
+	}
+	switch src := src.(type) {
+	case *Buffer[uint8]:
+		return processTilesInto(alg, src, dst, opts)
+	case *Buffer[uint16]:
+		return processTilesInto(alg, src, dst, opts)
+	case *Buffer[float32]:
+		return processTilesInto(alg, src, dst, opts)
+	}
+	return errors.New("uvpad: unsupported image type")
+}
+
+func processTilesInto[T Sample](alg Algorithm, src *Buffer[T], dst Image, opts Options) error {
+	out, ok := dst.(*Buffer[T])
+	if !ok {
+		return errors.New("uvpad: the source and destination of tiles differ in sample type")
+	}
+	if !opts.tiled(src.Width, src.Height) {
+		copy(out.Pix, alg.Process(src, opts).(*Buffer[T]).Pix)
+		return nil
+	}
+	processTiles(src, out, opts, alg.Process)
+	return nil
+}
+
+// processTiles runs process on every tile of opts.Tile pixels of src with a
+// margin of the radius around it, and assembles the tiles in out. With
+// EdgeWrap the margins of the tiles at the border are read from the opposite
+// side, so the tiles themselves don't wrap.
+func processTiles[T Sample](src, out *Buffer[T], opts Options, process func(Image, Options) Image) {
+	width, height := src.Width, src.Height
+	margin, size := opts.tileMargin(), opts.Tile
+	opts.Tile = 0
+	wrap := opts.Edge == EdgeWrap
+	if wrap {
+		opts.Edge = EdgeSkip
+	}
+
+	columns, rows := (width+size-1)/size, (height+size-1)/size
+	progress := opts.Progress
+	for ty := range rows {
+		for tx := range columns {
+			tile := image.Rect(tx*size, ty*size, (tx+1)*size, (ty+1)*size).Intersect(image.Rect(0, 0, width, height))
+			r := tile.Inset(-margin)
+			if !wrap {
+				r = r.Intersect(image.Rect(0, 0, width, height))
+			}
+
+			crop := NewBuffer[T](r.Dx(), r.Dy())
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				sy := (y%height + height) % height
+				for x := r.Min.X; x < r.Max.X; x++ {
+					sx := (x%width + width) % width
+					i, j := (sy*width+sx)*4, ((y-r.Min.Y)*crop.Width+x-r.Min.X)*4
+					copy(crop.Pix[j:j+4], src.Pix[i:i+4])
+				}
+			}
+
+			if progress != nil {
+				done, count := float64(ty*columns+tx), float64(rows*columns)
+				opts.Progress = func(fraction float64) { progress((done + fraction) / count) }
+			}
+			result := process(crop, opts).(*Buffer[T])
+			for y := tile.Min.Y; y < tile.Max.Y; y++ {
+				i, j := (y*width+tile.Min.X)*4, ((y-r.Min.Y)*crop.Width+tile.Min.X-r.Min.X)*4
+				copy(out.Pix[i:i+tile.Dx()*4], result.Pix[j:j+tile.Dx()*4])
+			}
+			if result != crop {
+				result.release()
+			}
+			crop.release()
+		}
+	}
+}
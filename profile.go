@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// profiles records the CPU profile, heap profile and execution trace asked
+// for by --cpuprofile, --memprofile and --trace while images are processed.
+type profiles struct {
+	cpu, trace *os.File
+	memory     string
+}
+
+// startProfiles starts the profiles requested by the flags of cmd.
+func startProfiles(cmd flagReader) (*profiles, error) {
+	p := &profiles{memory: cmd.String("memprofile")}
+	if name := cmd.String("cpuprofile"); name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		p.cpu = f
+	}
+	if name := cmd.String("trace"); name != "" {
+		f, err := os.Create(name)
+		if err == nil {
+			err = trace.Start(f)
+		}
+		if err != nil {
+			if f != nil {
+				f.Close()
+			}
+			if p.cpu != nil {
+				pprof.StopCPUProfile()
+				p.cpu.Close()
+			}
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+		p.trace = f
+	}
+	return p, nil
+}
+
+// stop ends the profiles and writes them. The heap profile is taken last,
+// and holds both what is still in use and everything allocated by the run.
+func (p *profiles) stop() error {
+	var errs []error
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, p.cpu.Close())
+	}
+	if p.trace != nil {
+		trace.Stop()
+		errs = append(errs, p.trace.Close())
+	}
+	if p.memory != "" {
+		errs = append(errs, writeHeapProfile(p.memory))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return nil
+}
+
+func writeHeapProfile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	// The in-use figures are only updated by a collection.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	progressWidth    = 30
+	progressInterval = 100 * time.Millisecond
+)
+
+// bar is the progress bar drawn below the messages on the console, nil when
+// the console isn't a terminal. It is guarded by consoleMu.
+var bar *progressBar
+
+// progressBar shows the progress of the file last reported and, for batches,
+// of the whole run with an estimate of the remaining time.
+type progressBar struct {
+	total    int
+	finished int
+	running  map[string]float64
+	current  string
+
+	start time.Time
+	drawn time.Time
+	shown bool
+}
+
+// startProgress enables the progress bar for total files if the console is
+// a terminal.
+func startProgress(total int) {
+	if !isTerminal(console) {
+		return
+	}
+	bar = &progressBar{
+		total:   total,
+		running: map[string]float64{},
+		start:   time.Now(),
+	}
+}
+
+// stopProgress erases the progress bar.
+func stopProgress() {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	bar.erase()
+	bar = nil
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressUpdater returns an uvpad.Options.Progress callback that advances
+// the bar for file.
+func progressUpdater(file string) func(float64) {
+	return func(fraction float64) {
+		consoleMu.Lock()
+		defer consoleMu.Unlock()
+
+		bar.running[file] = fraction
+		bar.current = file
+		if time.Since(bar.drawn) >= progressInterval {
+			bar.draw()
+		}
+	}
+}
+
+// finishProgress counts file as done.
+func finishProgress(file string) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+
+	if bar == nil {
+		return
+	}
+	delete(bar.running, file)
+	bar.finished++
+	bar.draw()
+}
+
+// erase removes the bar from the terminal and reports whether it was shown.
+func (p *progressBar) erase() bool {
+	if p == nil || !p.shown {
+		return false
+	}
+	fmt.Fprint(console, "\r\033[K")
+	p.shown = false
+	return true
+}
+
+func (p *progressBar) draw() {
+	if p == nil || p.finished == p.total {
+		p.erase()
+		return
+	}
+
+	var line string
+	if p.total == 1 {
+		fraction := p.running[p.current]
+		line = fmt.Sprintf("%s %s %5.1f%%", p.meter(fraction), p.current, fraction*100)
+		if eta, ok := p.eta(fraction); ok {
+			line += " ETA " + eta
+		}
+	} else {
+		done := float64(p.finished)
+		for _, fraction := range p.running {
+			done += fraction
+		}
+		overall := done / float64(p.total)
+		line = fmt.Sprintf("%s %d/%d files %5.1f%%", p.meter(overall), p.finished, p.total, overall*100)
+		if eta, ok := p.eta(overall); ok {
+			line += " ETA " + eta
+		}
+		if fraction, ok := p.running[p.current]; ok {
+			line += fmt.Sprintf("  %s %.0f%%", p.current, fraction*100)
+		}
+	}
+
+	fmt.Fprint(console, "\r\033[K"+line)
+	p.shown = true
+	p.drawn = time.Now()
+}
+
+func (p *progressBar) meter(fraction float64) string {
+	filled := min(int(fraction*progressWidth), progressWidth)
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", progressWidth-filled) + "]"
+}
+
+// eta estimates the remaining time from the time spent on the done fraction.
+func (p *progressBar) eta(fraction float64) (string, bool) {
+	elapsed := time.Since(p.start)
+	if fraction <= 0 || elapsed < time.Second {
+		return "", false
+	}
+	remaining := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+	return remaining.Round(time.Second).String(), true
+}
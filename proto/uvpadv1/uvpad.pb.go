@@ -0,0 +1,541 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: proto/uvpad.proto
+
+package uvpadv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ImageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ImageRequest_Settings
+	//	*ImageRequest_Upload
+	//	*ImageRequest_Image
+	Payload       isImageRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageRequest) Reset() {
+	*x = ImageRequest{}
+	mi := &file_proto_uvpad_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageRequest) ProtoMessage() {}
+
+func (x *ImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageRequest.ProtoReflect.Descriptor instead.
+func (*ImageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ImageRequest) GetPayload() isImageRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ImageRequest) GetSettings() *Settings {
+	if x != nil {
+		if x, ok := x.Payload.(*ImageRequest_Settings); ok {
+			return x.Settings
+		}
+	}
+	return nil
+}
+
+func (x *ImageRequest) GetUpload() *Upload {
+	if x != nil {
+		if x, ok := x.Payload.(*ImageRequest_Upload); ok {
+			return x.Upload
+		}
+	}
+	return nil
+}
+
+func (x *ImageRequest) GetImage() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*ImageRequest_Image); ok {
+			return x.Image
+		}
+	}
+	return nil
+}
+
+type isImageRequest_Payload interface {
+	isImageRequest_Payload()
+}
+
+type ImageRequest_Settings struct {
+	Settings *Settings `protobuf:"bytes,1,opt,name=settings,proto3,oneof"`
+}
+
+type ImageRequest_Upload struct {
+	Upload *Upload `protobuf:"bytes,2,opt,name=upload,proto3,oneof"`
+}
+
+type ImageRequest_Image struct {
+	Image []byte `protobuf:"bytes,3,opt,name=image,proto3,oneof"`
+}
+
+func (*ImageRequest_Settings) isImageRequest_Payload() {}
+
+func (*ImageRequest_Upload) isImageRequest_Payload() {}
+
+func (*ImageRequest_Image) isImageRequest_Payload() {}
+
+type Settings struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Settings) Reset() {
+	*x = Settings{}
+	mi := &file_proto_uvpad_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Settings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Settings) ProtoMessage() {}
+
+func (x *Settings) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Settings.ProtoReflect.Descriptor instead.
+func (*Settings) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Settings) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Upload struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Setting       string                 `protobuf:"bytes,1,opt,name=setting,proto3" json:"setting,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Upload) Reset() {
+	*x = Upload{}
+	mi := &file_proto_uvpad_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Upload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Upload) ProtoMessage() {}
+
+func (x *Upload) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Upload.ProtoReflect.Descriptor instead.
+func (*Upload) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Upload) GetSetting() string {
+	if x != nil {
+		return x.Setting
+	}
+	return ""
+}
+
+func (x *Upload) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Upload) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type ImageChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Format        string                 `protobuf:"bytes,1,opt,name=format,proto3" json:"format,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageChunk) Reset() {
+	*x = ImageChunk{}
+	mi := &file_proto_uvpad_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageChunk) ProtoMessage() {}
+
+func (x *ImageChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageChunk.ProtoReflect.Descriptor instead.
+func (*ImageChunk) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ImageChunk) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ImageChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type InfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoRequest) Reset() {
+	*x = InfoRequest{}
+	mi := &file_proto_uvpad_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoRequest) ProtoMessage() {}
+
+func (x *InfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{4}
+}
+
+type InfoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Algorithms    []string               `protobuf:"bytes,1,rep,name=algorithms,proto3" json:"algorithms,omitempty"`
+	Formats       []string               `protobuf:"bytes,2,rep,name=formats,proto3" json:"formats,omitempty"`
+	Settings      []*Setting             `protobuf:"bytes,3,rep,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_proto_uvpad_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InfoResponse) GetAlgorithms() []string {
+	if x != nil {
+		return x.Algorithms
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetFormats() []string {
+	if x != nil {
+		return x.Formats
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetSettings() []*Setting {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type Setting struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Usage         string                 `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"`
+	Default       string                 `protobuf:"bytes,3,opt,name=default,proto3" json:"default,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Setting) Reset() {
+	*x = Setting{}
+	mi := &file_proto_uvpad_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Setting) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Setting) ProtoMessage() {}
+
+func (x *Setting) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_uvpad_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Setting.ProtoReflect.Descriptor instead.
+func (*Setting) Descriptor() ([]byte, []int) {
+	return file_proto_uvpad_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Setting) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Setting) GetUsage() string {
+	if x != nil {
+		return x.Usage
+	}
+	return ""
+}
+
+func (x *Setting) GetDefault() string {
+	if x != nil {
+		return x.Default
+	}
+	return ""
+}
+
+var File_proto_uvpad_proto protoreflect.FileDescriptor
+
+const file_proto_uvpad_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/uvpad.proto\x12\buvpad.v1\"\x8f\x01\n" +
+	"\fImageRequest\x120\n" +
+	"\bsettings\x18\x01 \x01(\v2\x12.uvpad.v1.SettingsH\x00R\bsettings\x12*\n" +
+	"\x06upload\x18\x02 \x01(\v2\x10.uvpad.v1.UploadH\x00R\x06upload\x12\x16\n" +
+	"\x05image\x18\x03 \x01(\fH\x00R\x05imageB\t\n" +
+	"\apayload\"}\n" +
+	"\bSettings\x126\n" +
+	"\x06values\x18\x01 \x03(\v2\x1e.uvpad.v1.Settings.ValuesEntryR\x06values\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"J\n" +
+	"\x06Upload\x12\x18\n" +
+	"\asetting\x18\x01 \x01(\tR\asetting\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"8\n" +
+	"\n" +
+	"ImageChunk\x12\x16\n" +
+	"\x06format\x18\x01 \x01(\tR\x06format\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\r\n" +
+	"\vInfoRequest\"w\n" +
+	"\fInfoResponse\x12\x1e\n" +
+	"\n" +
+	"algorithms\x18\x01 \x03(\tR\n" +
+	"algorithms\x12\x18\n" +
+	"\aformats\x18\x02 \x03(\tR\aformats\x12-\n" +
+	"\bsettings\x18\x03 \x03(\v2\x11.uvpad.v1.SettingR\bsettings\"M\n" +
+	"\aSetting\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05usage\x18\x02 \x01(\tR\x05usage\x12\x18\n" +
+	"\adefault\x18\x03 \x01(\tR\adefault2\xbd\x01\n" +
+	"\x05Uvpad\x12:\n" +
+	"\x06Dilate\x12\x16.uvpad.v1.ImageRequest\x1a\x14.uvpad.v1.ImageChunk(\x010\x01\x12A\n" +
+	"\rDistanceField\x12\x16.uvpad.v1.ImageRequest\x1a\x14.uvpad.v1.ImageChunk(\x010\x01\x125\n" +
+	"\x04Info\x12\x15.uvpad.v1.InfoRequest\x1a\x16.uvpad.v1.InfoResponseB%Z#github.com/meir/uvpad/proto/uvpadv1b\x06proto3"
+
+var (
+	file_proto_uvpad_proto_rawDescOnce sync.Once
+	file_proto_uvpad_proto_rawDescData []byte
+)
+
+func file_proto_uvpad_proto_rawDescGZIP() []byte {
+	file_proto_uvpad_proto_rawDescOnce.Do(func() {
+		file_proto_uvpad_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_uvpad_proto_rawDesc), len(file_proto_uvpad_proto_rawDesc)))
+	})
+	return file_proto_uvpad_proto_rawDescData
+}
+
+var file_proto_uvpad_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_uvpad_proto_goTypes = []any{
+	(*ImageRequest)(nil), // 0: uvpad.v1.ImageRequest
+	(*Settings)(nil),     // 1: uvpad.v1.Settings
+	(*Upload)(nil),       // 2: uvpad.v1.Upload
+	(*ImageChunk)(nil),   // 3: uvpad.v1.ImageChunk
+	(*InfoRequest)(nil),  // 4: uvpad.v1.InfoRequest
+	(*InfoResponse)(nil), // 5: uvpad.v1.InfoResponse
+	(*Setting)(nil),      // 6: uvpad.v1.Setting
+	nil,                  // 7: uvpad.v1.Settings.ValuesEntry
+}
+var file_proto_uvpad_proto_depIdxs = []int32{
+	1, // 0: uvpad.v1.ImageRequest.settings:type_name -> uvpad.v1.Settings
+	2, // 1: uvpad.v1.ImageRequest.upload:type_name -> uvpad.v1.Upload
+	7, // 2: uvpad.v1.Settings.values:type_name -> uvpad.v1.Settings.ValuesEntry
+	6, // 3: uvpad.v1.InfoResponse.settings:type_name -> uvpad.v1.Setting
+	0, // 4: uvpad.v1.Uvpad.Dilate:input_type -> uvpad.v1.ImageRequest
+	0, // 5: uvpad.v1.Uvpad.DistanceField:input_type -> uvpad.v1.ImageRequest
+	4, // 6: uvpad.v1.Uvpad.Info:input_type -> uvpad.v1.InfoRequest
+	3, // 7: uvpad.v1.Uvpad.Dilate:output_type -> uvpad.v1.ImageChunk
+	3, // 8: uvpad.v1.Uvpad.DistanceField:output_type -> uvpad.v1.ImageChunk
+	5, // 9: uvpad.v1.Uvpad.Info:output_type -> uvpad.v1.InfoResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_proto_uvpad_proto_init() }
+func file_proto_uvpad_proto_init() {
+	if File_proto_uvpad_proto != nil {
+		return
+	}
+	file_proto_uvpad_proto_msgTypes[0].OneofWrappers = []any{
+		(*ImageRequest_Settings)(nil),
+		(*ImageRequest_Upload)(nil),
+		(*ImageRequest_Image)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_uvpad_proto_rawDesc), len(file_proto_uvpad_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_uvpad_proto_goTypes,
+		DependencyIndexes: file_proto_uvpad_proto_depIdxs,
+		MessageInfos:      file_proto_uvpad_proto_msgTypes,
+	}.Build()
+	File_proto_uvpad_proto = out.File
+	file_proto_uvpad_proto_goTypes = nil
+	file_proto_uvpad_proto_depIdxs = nil
+}
@@ -0,0 +1,186 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/uvpad.proto
+
+package uvpadv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Uvpad_Dilate_FullMethodName        = "/uvpad.v1.Uvpad/Dilate"
+	Uvpad_DistanceField_FullMethodName = "/uvpad.v1.Uvpad/DistanceField"
+	Uvpad_Info_FullMethodName          = "/uvpad.v1.Uvpad/Info"
+)
+
+// UvpadClient is the client API for Uvpad service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UvpadClient interface {
+	Dilate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImageRequest, ImageChunk], error)
+	DistanceField(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImageRequest, ImageChunk], error)
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+}
+
+type uvpadClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUvpadClient(cc grpc.ClientConnInterface) UvpadClient {
+	return &uvpadClient{cc}
+}
+
+func (c *uvpadClient) Dilate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImageRequest, ImageChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Uvpad_ServiceDesc.Streams[0], Uvpad_Dilate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImageRequest, ImageChunk]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uvpad_DilateClient = grpc.BidiStreamingClient[ImageRequest, ImageChunk]
+
+func (c *uvpadClient) DistanceField(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImageRequest, ImageChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Uvpad_ServiceDesc.Streams[1], Uvpad_DistanceField_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImageRequest, ImageChunk]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uvpad_DistanceFieldClient = grpc.BidiStreamingClient[ImageRequest, ImageChunk]
+
+func (c *uvpadClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, Uvpad_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UvpadServer is the server API for Uvpad service.
+// All implementations must embed UnimplementedUvpadServer
+// for forward compatibility.
+type UvpadServer interface {
+	Dilate(grpc.BidiStreamingServer[ImageRequest, ImageChunk]) error
+	DistanceField(grpc.BidiStreamingServer[ImageRequest, ImageChunk]) error
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	mustEmbedUnimplementedUvpadServer()
+}
+
+// UnimplementedUvpadServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUvpadServer struct{}
+
+func (UnimplementedUvpadServer) Dilate(grpc.BidiStreamingServer[ImageRequest, ImageChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method Dilate not implemented")
+}
+func (UnimplementedUvpadServer) DistanceField(grpc.BidiStreamingServer[ImageRequest, ImageChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DistanceField not implemented")
+}
+func (UnimplementedUvpadServer) Info(context.Context, *InfoRequest) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedUvpadServer) mustEmbedUnimplementedUvpadServer() {}
+func (UnimplementedUvpadServer) testEmbeddedByValue()               {}
+
+// UnsafeUvpadServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UvpadServer will
+// result in compilation errors.
+type UnsafeUvpadServer interface {
+	mustEmbedUnimplementedUvpadServer()
+}
+
+func RegisterUvpadServer(s grpc.ServiceRegistrar, srv UvpadServer) {
+	// If the following call pancis, it indicates UnimplementedUvpadServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Uvpad_ServiceDesc, srv)
+}
+
+func _Uvpad_Dilate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UvpadServer).Dilate(&grpc.GenericServerStream[ImageRequest, ImageChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uvpad_DilateServer = grpc.BidiStreamingServer[ImageRequest, ImageChunk]
+
+func _Uvpad_DistanceField_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UvpadServer).DistanceField(&grpc.GenericServerStream[ImageRequest, ImageChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Uvpad_DistanceFieldServer = grpc.BidiStreamingServer[ImageRequest, ImageChunk]
+
+func _Uvpad_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UvpadServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Uvpad_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UvpadServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Uvpad_ServiceDesc is the grpc.ServiceDesc for Uvpad service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Uvpad_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "uvpad.v1.Uvpad",
+	HandlerType: (*UvpadServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler:    _Uvpad_Info_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Dilate",
+			Handler:       _Uvpad_Dilate_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DistanceField",
+			Handler:       _Uvpad_DistanceField_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/uvpad.proto",
+}
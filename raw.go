@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// isRaw reports whether path is a raw image: RGBA samples without a header,
+// row by row, sized by --raw-size and --raw-depth.
+func isRaw(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".raw")
+}
+
+// rawSampleSizes are the bytes per sample of the depths of raw images.
+var rawSampleSizes = map[string]int{"8": 1, "16": 2, "float": 4}
+
+// runRaw pads a raw image without decoding it into memory: the input and
+// output files are memory-mapped and dilated tile by tile with --tile, so
+// images larger than the memory can be padded. It returns the hex SHA-256
+// digest of the output.
+func runRaw(j job) (string, error) {
+	s, opts := j.settings, j.opts
+	if j.input == "-" || j.output == "-" {
+		return "", fmt.Errorf("raw images must be read from and written to files")
+	}
+	if !isRaw(j.output) {
+		return "", fmt.Errorf("raw image %s must be written to a .raw file, got %s", j.input, j.output)
+	}
+	if s.rawSize == "" {
+		return "", fmt.Errorf("raw image %s needs --raw-size", j.input)
+	}
+	if s.sdf || s.mask != "" || s.mesh != "" || s.islands != "" || s.atlas != "" || s.grid != "" || opts.Packed || s.fields.requested() {
+		return "", fmt.Errorf("raw images can only be dilated, without masks, islands or extra outputs")
+	}
+	width, height, _ := parseDimensions(s.rawSize)
+	size := width * height * 4 * rawSampleSizes[s.rawDepth]
+
+	alg, err := uvpad.Lookup(s.algorithm)
+	if err != nil {
+		return "", err
+	}
+	if len(s.ops) > 0 {
+		alg = uvpad.Pipeline(alg, s.ops)
+	}
+	if s.radiusUV > 0 {
+		opts.Radius = s.radiusUV * float64(max(width, height))
+	}
+
+	in, err := os.Open(j.input)
+	if err != nil {
+		return "", withExitCode(exitRead, fmt.Errorf("failed to open input file: %w", err))
+	}
+	defer in.Close()
+	if info, err := in.Stat(); err != nil || info.Size() != int64(size) {
+		return "", withExitCode(exitRead, fmt.Errorf("raw image %s isn't %dx%d with --raw-depth %s", j.input, width, height, s.rawDepth))
+	}
+	input, unmapInput, err := mapFile(in, size, false)
+	if err != nil {
+		return "", withExitCode(exitRead, fmt.Errorf("failed to map input file: %w", err))
+	}
+	defer unmapInput()
+
+	logEvent(levelDebug, "settings", j.input, fields{
+		"algorithm": s.algorithm,
+		"width":     width,
+		"height":    height,
+		"tile":      opts.Tile,
+	}, "Processing raw %s (%dx%d) with %s in tiles of %d\n", j.input, width, height, s.algorithm, opts.Tile)
+
+	if s.backup {
+		if err := backup(j.input); err != nil {
+			return "", withExitCode(exitWrite, err)
+		}
+	}
+	hash := sha256.New()
+	err = writeAtomic(j.output, func(w io.Writer) error {
+		out := w.(*os.File)
+		if err := out.Truncate(int64(size)); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		output, unmapOutput, err := mapFile(out, size, true)
+		if err != nil {
+			return fmt.Errorf("failed to map output file: %w", err)
+		}
+		if err := uvpad.ProcessTiles(alg, rawBuffer(input, width, height, s.rawDepth), rawBuffer(output, width, height, s.rawDepth), opts); err != nil {
+			unmapOutput()
+			return err
+		}
+		hash.Write(output)
+		return unmapOutput()
+	})
+	if err != nil {
+		return "", withExitCode(exitWrite, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// rawBuffer returns a buffer whose samples are the bytes of a raw image of
+// the given depth, in the byte order of the machine.
+func rawBuffer(data []byte, width, height int, depth string) uvpad.Image {
+	switch depth {
+	case "16":
+		return &uvpad.Buffer[uint16]{Pix: unsafe.Slice((*uint16)(unsafe.Pointer(unsafe.SliceData(data))), len(data)/2), Width: width, Height: height}
+	case "float":
+		return &uvpad.Buffer[float32]{Pix: unsafe.Slice((*float32)(unsafe.Pointer(unsafe.SliceData(data))), len(data)/4), Width: width, Height: height}
+	default:
+		return &uvpad.Buffer[uint8]{Pix: data, Width: width, Height: height}
+	}
+}
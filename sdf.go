@@ -0,0 +1,59 @@
+package main
+
+import (
+	"image"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+)
+
+// sdfDepths are the sample depths of --sdf-depth.
+var sdfDepths = []string{"8", "16", "float"}
+
+// signedDistanceField returns the signed distance field of img at the
+// sample depth of --sdf-depth, the depth of img if empty. Float fields keep
+// the distances beyond the spread, written as they are to exr outputs. When
+// the field leaves color channels free, they hold the dilation of img.
+func signedDistanceField(s settings, alg uvpad.Algorithm, img image.Image) image.Image {
+	src := convertDepth(uvpad.FromImage(img), s.sdfDepth)
+
+	channels := s.opts.SDFChannels
+	if channels != 0 && channels&uvpad.ChannelsRGB != uvpad.ChannelsRGB {
+		// Keeping the alpha of the dilation keeps the coverage of the field.
+		dilateOpts := s.opts
+		dilateOpts.KeepAlpha = true
+		dilateOpts.Progress = nil
+		src = alg.Process(src, dilateOpts)
+	}
+
+	field := uvpad.SignedDistanceField(src, s.opts)
+	if b, ok := field.(*uvpad.Buffer[float32]); ok {
+		return uvpad.FloatImage{Buffer: b}
+	}
+	return field.Image()
+}
+
+// convertDepth converts img to the sample depth named by depth, leaving it
+// unchanged if depth is empty.
+func convertDepth(img uvpad.Image, depth string) uvpad.Image {
+	switch b := img.(type) {
+	case *uvpad.Buffer[uint8]:
+		return convertBuffer(b, depth)
+	case *uvpad.Buffer[uint16]:
+		return convertBuffer(b, depth)
+	case *uvpad.Buffer[float32]:
+		return convertBuffer(b, depth)
+	}
+	return img
+}
+
+func convertBuffer[T uvpad.Sample](b *uvpad.Buffer[T], depth string) uvpad.Image {
+	switch depth {
+	case "8":
+		return uvpad.Convert[uint8](b)
+	case "16":
+		return uvpad.Convert[uint16](b)
+	case "float":
+		return uvpad.Convert[float32](b)
+	}
+	return b
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/urfave/cli/v3"
+)
+
+// serverFlags are the settings that write files or reports next to the
+// server rather than into the answer, which a request can't set.
+var serverFlags = []string{"debug-wireframe", "mesh-report"}
+
+// serveCommand dilates the images posted to it over HTTP, for services that
+// call uvpad rather than run it.
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "Serve dilation over HTTP: POST an image to / and get the padded image back, with the settings of the other flags as defaults",
+	Description: "The image is the request body, or the \"image\" part of a multipart/form-data request. " +
+		"Settings are query parameters or form fields named like the flags, such as ?algorithm=gimp&radius=8, " +
+		"and --mask, --mesh and the other files are uploaded as parts of a multipart request. " +
+		"The result is in --format, or the format of the image if empty.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "listen",
+			Value: ":8080",
+			Usage: "Address the server listens on, such as :8080 or localhost:8080",
+		},
+		&cli.StringFlag{
+			Name:  "grpc",
+			Usage: "Also serve the gRPC service of proto/uvpad.proto on this address, such as :9090",
+		},
+		&cli.StringFlag{
+			Name:  "max-upload",
+			Value: "256MiB",
+			Usage: "Largest request accepted, images and uploaded files together",
+			Validator: func(size string) error {
+				_, err := parseSize(size)
+				return err
+			},
+		},
+	},
+	Action: func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.NArg() != 0 {
+			return fmt.Errorf("serve takes no arguments, usage: uvpad serve [--listen :8080]")
+		}
+		if err := loadConfig(cmd.Root()); err != nil {
+			return err
+		}
+		limitThreads(cmd)
+		if err := setVerbosity(cmd); err != nil {
+			return err
+		}
+
+		budget, _ := parseSize(cmd.String("max-memory"))
+		if budget == 0 {
+			budget = availableMemory()
+		}
+		maxUpload, _ := parseSize(cmd.String("max-upload"))
+		handler := &server{cmd: cmd, budget: newMemoryBudget(budget), maxMemory: budget, maxUpload: maxUpload}
+		srv := &http.Server{
+			Addr:              cmd.String("listen"),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			// Requests being dilated are finished before exiting.
+			srv.Shutdown(context.Background())
+		}()
+
+		grpcErr := make(chan error, 1)
+		if addr := cmd.String("grpc"); addr != "" {
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return withExitCode(exitRead, fmt.Errorf("failed to listen on %s: %w", addr, err))
+			}
+			grpcSrv := newGRPCServer(handler)
+			go func() {
+				<-ctx.Done()
+				grpcSrv.GracefulStop()
+			}()
+			go func() {
+				if err := grpcSrv.Serve(listener); err != nil {
+					grpcErr <- err
+					stop()
+				}
+			}()
+			logEvent(levelNormal, "listening", "", fields{"address": addr, "protocol": "grpc"}, "Serving gRPC on %s\n", addr)
+		}
+
+		logEvent(levelNormal, "listening", "", fields{"address": srv.Addr}, "Listening on %s\n", srv.Addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return withExitCode(exitRead, fmt.Errorf("failed to serve: %w", err))
+		}
+		select {
+		case err := <-grpcErr:
+			return withExitCode(exitRead, fmt.Errorf("failed to serve gRPC: %w", err))
+		default:
+			return nil
+		}
+	},
+}
+
+// server dilates every request with the settings of the command line,
+// overridden by those of the request, within a memory budget shared by the
+// requests being served. Requests that need more than the whole budget are
+// refused before their image is decoded.
+type server struct {
+	cmd       *cli.Command
+	budget    *memoryBudget
+	maxMemory int64
+	maxUpload int64
+}
+
+func (srv *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status, err := srv.serve(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+	}
+	logEvent(levelNormal, "request", "", fields{
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+		"status":  status,
+		"seconds": time.Since(start).Seconds(),
+	}, "%s %s from %s: %d %s in %v\n", r.Method, r.URL.Path, r.RemoteAddr, status, http.StatusText(status), time.Since(start).Round(time.Millisecond))
+}
+
+// serve answers a request with the padded image, or returns the status and
+// error to answer with.
+func (srv *server) serve(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.URL.Path != "/" {
+		return http.StatusNotFound, fmt.Errorf("not found, POST images to /")
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, POST images to /", r.Method)
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, srv.maxUpload)
+
+	values := map[string]any{}
+	for name, v := range r.URL.Query() {
+		if err := srv.setParam(values, name, v[len(v)-1]); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	name := "request"
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		dir, err := os.MkdirTemp("", "uvpad-serve")
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("failed to store the uploaded files: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		name, data, err = srv.readMultipart(r, values, dir)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return http.StatusRequestEntityTooLarge, err
+			}
+			return http.StatusBadRequest, err
+		}
+	} else {
+		var err error
+		data, err = io.ReadAll(r.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return http.StatusRequestEntityTooLarge, err
+			}
+			return http.StatusBadRequest, fmt.Errorf("failed to read the image: %w", err)
+		}
+	}
+	if len(data) == 0 {
+		return http.StatusBadRequest, fmt.Errorf("no image, POST it as the body or the \"image\" part of a multipart form")
+	}
+
+	padded, format, err := srv.dilate(name, data, values)
+	if err != nil {
+		if errors.Is(err, errOverBudget) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+		switch exitCode(err) {
+		case exitRead, exitUsage:
+			return http.StatusBadRequest, err
+		case exitVerify:
+			return http.StatusUnprocessableEntity, err
+		default:
+			return http.StatusInternalServerError, err
+		}
+	}
+
+	contentType := mime.TypeByExtension("." + format)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(padded)))
+	w.Write(padded)
+	return http.StatusOK, nil
+}
+
+// dilate pads the encoded image data with the settings of the command line
+// overridden by values, and returns it encoded in --format, or the format of
+// the image, along with that format.
+func (srv *server) dilate(name string, data []byte, values map[string]any) ([]byte, string, error) {
+	j := job{input: name, settings: newSettings(overrides{srv.cmd, values})}
+	j.budget, j.maxMemory = srv.budget, srv.maxMemory
+
+	var padded bytes.Buffer
+	var encoded string
+	err := pad(j, name, bytes.NewReader(data), func(img image.Image, format string) error {
+		if j.format != "" {
+			format = j.format
+		}
+		encoded = format
+		if err := uvpad.Encode(&padded, img, format); err != nil {
+			return withExitCode(exitWrite, fmt.Errorf("failed to encode the padded image: %w", err))
+		}
+		return nil
+	})
+	return padded.Bytes(), encoded, err
+}
+
+// readMultipart reads the image and the settings of a multipart request,
+// storing the uploaded files of fileFlags in dir. It returns the file name
+// of the image and its data.
+func (srv *server) readMultipart(r *http.Request, values map[string]any, dir string) (string, []byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := "request"
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the form: %w", err)
+		}
+
+		field := part.FormName()
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read %q: %w", field, err)
+		}
+		switch {
+		case field == "image":
+			data = content
+			if part.FileName() != "" {
+				name = filepath.Base(part.FileName())
+			}
+		case slices.Contains(fileFlags, field):
+			// The extension tells the format of meshes and atlases.
+			path := filepath.Join(dir, field+filepath.Ext(part.FileName()))
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				return "", nil, fmt.Errorf("failed to store %q: %w", field, err)
+			}
+			values[field] = path
+		default:
+			if err := srv.setParam(values, field, string(content)); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return name, data, nil
+}
+
+// setParam validates the value of the setting name, given as text, and adds
+// it to values.
+func (srv *server) setParam(values map[string]any, name, value string) error {
+	// Files can only be uploaded, never named by a path on the server.
+	if slices.Contains(fileFlags, name) {
+		return fmt.Errorf("%q must be uploaded as a file, never named by a path on the server", name)
+	}
+	if slices.Contains(serverFlags, name) {
+		return fmt.Errorf("%q can't be set by a request", name)
+	}
+
+	// validateOverride takes the types of JSON values.
+	var v any = value
+	for _, flag := range srv.cmd.Root().Flags {
+		if !slices.Contains(flag.Names(), name) {
+			continue
+		}
+		switch flag.(type) {
+		case *cli.BoolFlag:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%q must be a boolean, got %q", name, value)
+			}
+			v = b
+		case *cli.IntFlag, *cli.FloatFlag:
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("%q must be a number, got %q", name, value)
+			}
+			v = f
+		}
+	}
+
+	v, err := validateOverride(srv.cmd.Root(), name, v)
+	if err != nil {
+		return err
+	}
+	values[name] = v
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/meir/uvpad/pkg/uvpad"
+	"github.com/urfave/cli/v3"
+)
+
+// settings are the processing options of a job.
+type settings struct {
+	algorithm   string
+	ops         []uvpad.Op
+	radiusUV    float64
+	noAlpha     string
+	sdf         bool
+	sdfDepth    string
+	mask        string
+	mesh        string
+	meshFilter  uvpad.MeshFilter
+	perIsland   bool
+	stitchSeams bool
+	meshReport  bool
+	// debugWireframe also writes the output with the UV wireframe of the
+	// mesh drawn over it in wireframeColor.
+	debugWireframe bool
+	wireframeColor uvpad.Color
+	islands        string
+	islandMap      string
+	atlas          string
+	grid           string
+	gridPadding    int
+	gutter         uvpad.Gutter
+	packedMasks    [3]string
+	format         string
+	rawSize        string
+	rawDepth       string
+	fields         fieldOutputs
+	inPlace        bool
+	backup         bool
+	maxMemory      int64
+	fingerprint    string
+	budget         *memoryBudget
+	state          *state
+	checkpoint     *checkpoint
+	opts           uvpad.Options
+}
+
+// flagReader reads flag values, either straight from the command line or with
+// the per-job overrides of a manifest on top.
+type flagReader interface {
+	String(name string) string
+	Bool(name string) bool
+	Int(name string) int64
+	Float(name string) float64
+}
+
+// overrides layers validated per-job values over the command line flags.
+type overrides struct {
+	*cli.Command
+	values map[string]any
+}
+
+func (o overrides) String(name string) string {
+	if v, ok := o.values[name].(string); ok {
+		return v
+	}
+	return o.Command.String(name)
+}
+
+func (o overrides) Bool(name string) bool {
+	if v, ok := o.values[name].(bool); ok {
+		return v
+	}
+	return o.Command.Bool(name)
+}
+
+func (o overrides) Int(name string) int64 {
+	if v, ok := o.values[name].(int64); ok {
+		return v
+	}
+	return o.Command.Int(name)
+}
+
+func (o overrides) Float(name string) float64 {
+	if v, ok := o.values[name].(float64); ok {
+		return v
+	}
+	return o.Command.Float(name)
+}
+
+// newSettings reads the processing settings of a job.
+func newSettings(r flagReader) settings {
+	algorithm := r.String("algorithm")
+	if r.Bool("slower") {
+		algorithm = "gimp"
+	}
+
+	edge, _ := uvpad.ParseEdge(r.String("edge"))
+	if r.Bool("wrap") {
+		edge = uvpad.EdgeWrap
+	}
+
+	variant, _ := uvpad.ParseJFAVariant(r.String("jfa-variant"))
+	metric, _ := uvpad.ParseMetric(r.String("metric"))
+	falloff, _ := uvpad.ParseFalloff(r.String("fade"))
+	fadeColor, _ := uvpad.ParseColor(r.String("fade-color"))
+	background, _ := uvpad.ParseBackground(r.String("background"))
+	normalZ, _ := uvpad.ParseNormalZ(r.String("normal-z"))
+	colorSpace, _ := uvpad.ParseColorSpace(r.String("color-space"))
+	channels, _ := uvpad.ParseChannels(r.String("channels"))
+	seed, _ := uvpad.ParseSeed(r.String("seed"))
+	sign, _ := uvpad.ParseSign(r.String("sdf-sign"))
+	gutter, _ := uvpad.ParseGutter(r.String("gutter"))
+	sdfChannels, _ := uvpad.ParseChannels(r.String("sdf-channels"))
+	wireframeColor, _ := uvpad.ParseColor(r.String("wireframe-color"))
+
+	// A texel of mip level N covers 2^N texels of the image, so the padding
+	// must reach that far for the gutters to keep the colors of their
+	// islands.
+	radius := r.Float("radius")
+	if mips := r.Int("mips"); mips > 0 {
+		radius = math.Ldexp(1, int(mips))
+	}
+
+	s := settings{
+		algorithm:      algorithm,
+		noAlpha:        r.String("no-alpha"),
+		radiusUV:       r.Float("radius-uv"),
+		sdf:            r.Bool("sdf") || r.Bool("msdf"),
+		sdfDepth:       r.String("sdf-depth"),
+		mask:           r.String("mask"),
+		mesh:           r.String("mesh"),
+		perIsland:      r.Bool("per-island"),
+		stitchSeams:    r.Bool("stitch-seams"),
+		meshReport:     r.Bool("mesh-report"),
+		debugWireframe: r.Bool("debug-wireframe"),
+		wireframeColor: wireframeColor,
+		islands:        r.String("islands"),
+		islandMap:      r.String("island-map"),
+		atlas:          r.String("atlas"),
+		grid:           r.String("grid"),
+		gridPadding:    int(r.Int("grid-padding")),
+		gutter:         gutter,
+		meshFilter: uvpad.MeshFilter{
+			Mesh:      r.String("mesh-object"),
+			Primitive: r.String("mesh-primitive"),
+			Material:  r.String("mesh-material"),
+			UVSet:     r.String("uv-set"),
+		},
+		packedMasks: [3]string{r.String("mask-r"), r.String("mask-g"), r.String("mask-b")},
+		format:      formatName(r.String("format")),
+		rawSize:     r.String("raw-size"),
+		rawDepth:    r.String("raw-depth"),
+		fields: fieldOutputs{
+			voronoi:       r.String("debug-voronoi"),
+			distance:      r.String("debug-distance"),
+			export:        r.String("export-distance"),
+			exportNearest: r.Bool("export-nearest"),
+			offsets:       r.String("offset-map"),
+		},
+		inPlace: r.Bool("in-place"),
+		backup:  r.Bool("in-place") && !r.Bool("no-backup"),
+		opts: uvpad.Options{
+			Bias:           r.Float("bias"),
+			ColorSpace:     colorSpace,
+			NormalMap:      r.Bool("normal-map"),
+			NormalZ:        normalZ,
+			Open:           r.Float("open"),
+			Close:          r.Float("close"),
+			Erode:          r.Float("erode"),
+			Radius:         radius,
+			Tile:           int(r.Int("tile")),
+			AlphaThreshold: float64(r.Int("alpha-threshold")) / 255,
+			Edge:           edge,
+			JFA:            variant,
+			Metric:         metric,
+			Blend:          int(r.Int("blend")),
+			Iterations:     int(r.Int("iterations")),
+			Connectivity:   int(r.Int("connectivity")),
+			Kernel:         int(r.Int("kernel")),
+			Composite:      r.Bool("composite"),
+			KeepAlpha:      r.Bool("keep-alpha"),
+			Channels:       channels,
+			Seed:           seed,
+			Threshold:      r.Float("threshold"),
+			KeyTolerance:   r.Float("key-tolerance"),
+			Invert:         r.Bool("invert"),
+			Packed:         r.Bool("packed"),
+			Premultiplied:  r.Bool("premultiplied"),
+			FadeDistance:   r.Float("fade-distance"),
+			Fade:           falloff,
+			FadeColor:      fadeColor,
+			Feather:        r.Float("feather"),
+			Background:     background,
+			Spread:         r.Float("spread"),
+			MSDF:           r.Bool("msdf"),
+			SDFSign:        sign,
+			SDFChannels:    sdfChannels,
+		},
+	}
+	if r.String("ops") != "" {
+		s.ops, _ = uvpad.ParseOps(r.String("ops"))
+	}
+	if r.String("key-color") != "" {
+		keyColor, _ := uvpad.ParseColor(r.String("key-color"))
+		s.opts.KeyColor = &keyColor
+	}
+	if r.String("max-memory") != "" {
+		s.maxMemory, _ = parseSize(r.String("max-memory"))
+	}
+	return s
+}
+
+// batchFlags are the flags that apply to a run as a whole and can't be
+// overridden per job.
+var batchFlags = []string{
+	"config", "preset", "manifest", "output", "output-dir", "suffix",
+	"recursive", "in-place", "no-backup", "jobs", "threads", "max-memory", "checksums",
+	"incremental", "state-file", "checkpoint", "log-format", "quiet", "verbose", "debug",
+	"debug-voronoi", "debug-distance", "offset-map", "export-distance", "export-nearest",
+	"island-map", "cpuprofile", "memprofile", "trace",
+}
+
+// validateOverride checks a per-job value against the type and validator of
+// the flag it overrides, returning it converted to the flag's type.
+func validateOverride(cmd *cli.Command, name string, value any) (any, error) {
+	if name == "help" || slices.Contains(batchFlags, name) {
+		return nil, fmt.Errorf("%q can't be set per job", name)
+	}
+
+	for _, flag := range cmd.Flags {
+		if !slices.Contains(flag.Names(), name) {
+			continue
+		}
+
+		switch f := flag.(type) {
+		case *cli.StringFlag:
+			v, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a string", name)
+			}
+			return v, validate(f.Validator, v)
+		case *cli.BoolFlag:
+			v, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a boolean", name)
+			}
+			return v, validate(f.Validator, v)
+		case *cli.IntFlag:
+			v, ok := value.(float64)
+			if !ok || v != float64(int64(v)) {
+				return nil, fmt.Errorf("%q must be an integer", name)
+			}
+			return int64(v), validate(f.Validator, int64(v))
+		case *cli.FloatFlag:
+			v, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%q must be a number", name)
+			}
+			return v, validate(f.Validator, v)
+		default:
+			return nil, fmt.Errorf("%q can't be set per job", name)
+		}
+	}
+	return nil, fmt.Errorf("unknown setting %q", name)
+}
+
+func validate[T any](validator func(T) error, value T) error {
+	if validator == nil {
+		return nil
+	}
+	return validator(value)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// fileFlags are the settings naming files that are read to produce outputs.
+var fileFlags = []string{"mask", "mask-r", "mask-g", "mask-b", "mesh", "islands", "atlas"}
+
+// fingerprint summarizes every setting that affects the pixels of outputs,
+// which are all but the batch flags, with per-job overrides applied, and the
+// size and modification time of the files they name. --max-memory is a batch
+// flag, but it decides whether images are tiled or reduced to 8-bit samples
+// to fit.
+func fingerprint(cmd *cli.Command, overrides map[string]any) string {
+	var names []string
+	for _, flag := range cmd.Flags {
+		name := flag.Names()[0]
+		if name != "help" && !slices.Contains(batchFlags, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	hash := sha256.New()
+	maxMemory, _ := parseSize(cmd.String("max-memory"))
+	fmt.Fprintf(hash, "max-memory=%d\n", maxMemory)
+	for _, name := range names {
+		value, ok := overrides[name]
+		if !ok {
+			value = cmd.Value(name)
+		}
+		fmt.Fprintf(hash, "%s=%v\n", name, value)
+		if path, ok := value.(string); ok && path != "" && slices.Contains(fileFlags, name) {
+			stamp, _ := stat(path)
+			fmt.Fprintf(hash, "%s:%d:%d\n", name, stamp.Size, stamp.ModTime.UnixNano())
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fileStamp identifies a version of a file cheaply.
+type fileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+func stat(name string) (fileStamp, bool) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return fileStamp{}, false
+	}
+	return fileStamp{info.Size(), info.ModTime()}, true
+}
+
+// stateEntry records how an output was produced.
+type stateEntry struct {
+	Input        fileStamp `json:"input"`
+	InputDigest  string    `json:"input_sha256"`
+	Output       fileStamp `json:"output"`
+	OutputDigest string    `json:"output_sha256"`
+	Settings     string    `json:"settings"`
+}
+
+// state is the --incremental record of produced outputs, keyed by absolute
+// output path.
+type state struct {
+	mu      sync.Mutex
+	path    string
+	Entries map[string]stateEntry `json:"entries"`
+}
+
+func loadState(path string) (*state, error) {
+	st := &state{path: path, Entries: map[string]stateEntry{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	return st, nil
+}
+
+func (st *state) save() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return writeAtomic(st.path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	})
+}
+
+func stateKey(output string) string {
+	if abs, err := filepath.Abs(output); err == nil {
+		return abs
+	}
+	return output
+}
+
+// current returns the recorded digest of the job's output if it is up to date
+// with its input and the current settings.
+func (st *state) current(j job) (string, bool) {
+	if j.input == "-" || j.output == "-" {
+		return "", false
+	}
+
+	st.mu.Lock()
+	entry, ok := st.Entries[stateKey(j.output)]
+	st.mu.Unlock()
+	if !ok || entry.Settings != j.fingerprint {
+		return "", false
+	}
+
+	output, ok := stat(j.output)
+	if !ok || !output.equal(entry.Output) {
+		return "", false
+	}
+	if sameFile(j.input, j.output) {
+		// Padded in place: the input is the output we produced.
+		return entry.OutputDigest, true
+	}
+
+	input, ok := stat(j.input)
+	if !ok {
+		return "", false
+	}
+	if input.equal(entry.Input) {
+		return entry.OutputDigest, true
+	}
+	digest, err := fileDigest(j.input)
+	if err != nil || digest != entry.InputDigest {
+		return "", false
+	}
+	return entry.OutputDigest, true
+}
+
+// record remembers a freshly produced output. inputStamp and inputDigest
+// describe the input as it was read.
+func (st *state) record(j job, input fileStamp, inputDigest, outputDigest string) {
+	if j.input == "-" || j.output == "-" {
+		return
+	}
+	output, ok := stat(j.output)
+	if !ok {
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.Entries[stateKey(j.output)] = stateEntry{
+		Input:        input,
+		InputDigest:  inputDigest,
+		Output:       output,
+		OutputDigest: outputDigest,
+		Settings:     j.fingerprint,
+	}
+}
+
+func (a fileStamp) equal(b fileStamp) bool {
+	return a.Size == b.Size && a.ModTime.Equal(b.ModTime)
+}
+
+// fileDigest returns the hex SHA-256 digest of a file.
+func fileDigest(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
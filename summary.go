@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// result is the outcome of processing one job.
+type result struct {
+	job
+	digest   string
+	duration time.Duration
+	skipped  bool
+	err      error
+}
+
+// printSummary prints one status line per processed file, or a summary
+// event with the counts for --log-format json.
+func printSummary(results []result) {
+	if jsonLog {
+		var ok, skipped, failed int
+		for _, r := range results {
+			switch {
+			case r.err != nil:
+				failed++
+			case r.skipped:
+				skipped++
+			default:
+				ok++
+			}
+		}
+		logEvent(levelNormal, "summary", "", fields{"ok": ok, "skipped": skipped, "failed": failed}, "")
+		return
+	}
+
+	printf("\nSummary:\n")
+	for _, r := range results {
+		if r.err != nil {
+			printf("  failed  %s: %v\n", r.input, r.err)
+		} else if r.skipped {
+			printf("  skipped %s: %s is up to date\n", r.input, r.output)
+		} else {
+			printf("  ok      %s -> %s (%v)\n", r.input, r.output, r.duration.Round(time.Millisecond))
+		}
+	}
+}
+
+// failures returns an error describing how many results failed, or nil. It
+// carries the exit code of the first failure.
+func failures(results []result) error {
+	var first error
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			if first == nil {
+				first = r.err
+			}
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if len(results) == 1 {
+		return first
+	}
+	return withExitCode(exitCode(first), fmt.Errorf("%d of %d files failed", failed, len(results)))
+}